@@ -9,16 +9,32 @@ import (
 	"cloud.google.com/go/storage"
 )
 
+// GCSFileInfo is the fs.FileInfo returned by GCSFS that provides the
+// generation and metageneration of the object. Directories return 0.
+//
+//	info, err := fsys.Stat("file.txt")
+//	if err != nil {
+//	  log.Fatal(err)
+//	}
+//	gen := info.(gcsfs.GCSFileInfo).Generation()
+type GCSFileInfo interface {
+	fs.FileInfo
+	Generation() int64
+	Metageneration() int64
+}
+
 type content struct {
 	name    string
 	isDir   bool
 	size    int64
 	modTime time.Time
+	attrs   *storage.ObjectAttrs
 }
 
 var (
 	_ fs.DirEntry = (*content)(nil)
 	_ fs.FileInfo = (*content)(nil)
+	_ GCSFileInfo = (*content)(nil)
 )
 
 func newContent(attrs *storage.ObjectAttrs) *content {
@@ -40,6 +56,7 @@ func newFileContent(attrs *storage.ObjectAttrs) *content {
 		name:    path.Base(attrs.Name),
 		size:    attrs.Size,
 		modTime: attrs.Updated,
+		attrs:   attrs,
 	}
 }
 
@@ -63,12 +80,32 @@ func (c *content) ModTime() time.Time {
 	return c.modTime
 }
 
+// Generation returns the generation of the object.
+func (c *content) Generation() int64 {
+	if c.attrs == nil {
+		return 0
+	}
+	return c.attrs.Generation
+}
+
+// Metageneration returns the metageneration of the object.
+func (c *content) Metageneration() int64 {
+	if c.attrs == nil {
+		return 0
+	}
+	return c.attrs.Metageneration
+}
+
 func (c *content) IsDir() bool {
 	return c.isDir
 }
 
+// Sys returns the *storage.ObjectAttrs of this content, or nil if this content is directory.
 func (c *content) Sys() interface{} {
-	return nil
+	if c.attrs == nil {
+		return nil
+	}
+	return c.attrs
 }
 
 func (c *content) Type() fs.FileMode {
@@ -1,6 +1,7 @@
 package gcsfs
 
 import (
+	"context"
 	"io"
 	"io/fs"
 	"sort"
@@ -11,19 +12,23 @@ import (
 
 type gcsDir struct {
 	*content
+	ctx    context.Context
+	cancel context.CancelFunc
 	fsys   *GCSFS
 	prefix string
 	offset string
 	cache  []fs.DirEntry
+	marker bool
 	eof    bool
 }
 
 var _ fs.ReadDirFile = (*gcsDir)(nil)
 
-func newGcsDir(fsys *GCSFS, prefix string) *gcsDir {
+func newGcsDir(ctx context.Context, fsys *GCSFS, prefix string) *gcsDir {
 	prefix = normalizePrefix(fsys.key(prefix))
 	return &gcsDir{
 		content: newDirContent(prefix),
+		ctx:     ctx,
 		fsys:    fsys,
 		prefix:  prefix,
 	}
@@ -41,6 +46,10 @@ func (d *gcsDir) Stat() (fs.FileInfo, error) {
 
 // Close closes streams.
 func (d *gcsDir) Close() error {
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
 	return nil
 }
 
@@ -98,7 +107,7 @@ func (d *gcsDir) list(n int) ([]fs.DirEntry, error) {
 		return nil, err
 	}
 	query := newQuery("/", d.prefix, d.offset)
-	it := c.bucket(d.fsys.bucket).objects(d.fsys.Context(), query)
+	it := d.fsys.bucketHandle(c).objects(d.ctx, query)
 	for {
 		attrs, err := it.nextAttrs()
 		if err == iterator.Done {
@@ -108,6 +117,10 @@ func (d *gcsDir) list(n int) ([]fs.DirEntry, error) {
 		if err != nil {
 			return nil, err
 		}
+		if isDirMarker(attrs) {
+			d.marker = d.marker || attrs.Name == d.prefix
+			continue
+		}
 		content := newContent(attrs)
 		if d.offset >= content.Name() {
 			continue
@@ -124,14 +137,14 @@ func (d *gcsDir) list(n int) ([]fs.DirEntry, error) {
 }
 
 // Open called by GCSFS.Open(name string).
-// Open calls d.list(n), if the results is empty then returns a PathError
-// otherwise sets the results as d.cache.
+// Open calls d.list(n), if the results is empty and there is no directory marker
+// then returns a PathError otherwise sets the results as d.cache.
 func (d *gcsDir) open(n int) (*gcsDir, error) {
 	entries, err := d.list(n)
 	if err != nil {
 		return nil, err
 	}
-	if len(entries) == 0 {
+	if len(entries) == 0 && !d.marker {
 		return nil, &fs.PathError{Op: "Open", Path: d.prefix, Err: fs.ErrNotExist}
 	}
 	d.cache = entries
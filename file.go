@@ -1,6 +1,8 @@
 package gcsfs
 
 import (
+	"context"
+	"hash/crc32"
 	"io"
 	"io/fs"
 	"path"
@@ -11,36 +13,176 @@ import (
 
 type gcsFile struct {
 	*content
-	fsys  *GCSFS
-	obj   gcsObject
-	attrs *storage.ObjectAttrs
-	in    io.ReadCloser
+	ctx    context.Context
+	fsys   *GCSFS
+	obj    gcsObject
+	attrs  *storage.ObjectAttrs
+	in     io.ReadCloser
+	cancel context.CancelFunc
+	offset int64
+	// crc is the CRC32C of the bytes read sequentially from the start.
+	crc      uint32
+	crcValid bool
 }
 
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 var (
 	_ fs.File     = (*gcsFile)(nil)
 	_ fs.FileInfo = (*gcsFile)(nil)
+	_ io.Seeker   = (*gcsFile)(nil)
+	_ io.ReaderAt = (*gcsFile)(nil)
+	_ io.WriterTo = (*gcsFile)(nil)
 )
 
-func newGcsFile(fsys *GCSFS, obj gcsObject, attrs *storage.ObjectAttrs) *gcsFile {
+func newGcsFile(ctx context.Context, fsys *GCSFS, obj gcsObject, attrs *storage.ObjectAttrs) *gcsFile {
 	return &gcsFile{
-		content: newFileContent(attrs),
-		fsys:    fsys,
-		obj:     obj,
-		attrs:   attrs,
+		content:  newFileContent(attrs),
+		ctx:      ctx,
+		fsys:     fsys,
+		obj:      obj,
+		attrs:    attrs,
+		crcValid: true,
 	}
 }
 
+func (f *gcsFile) openReader() error {
+	if f.in != nil {
+		return nil
+	}
+	var err error
+	// NOTE: Pin the generation of attrs so that the bytes match its size and CRC32C.
+	if f.offset == 0 {
+		f.in, err = f.obj.newReader(f.ctx, f.attrs.Generation)
+	} else {
+		f.in, err = f.obj.newRangeReader(f.ctx, f.attrs.Generation, f.offset, -1)
+	}
+	if isObjectNotExist(err) {
+		// NOTE: The cached generation has gone, so Open again gets the latest.
+		f.fsys.invalidateAttrs(f.attrs.Name)
+	}
+	return err
+}
+
+// seekedToEnd reports whether Seek has moved the offset to the end of this file
+// before the reader is opened.
+func (f *gcsFile) seekedToEnd() bool {
+	return f.in == nil && f.offset > 0 && f.offset >= f.attrs.Size
+}
+
 // Read reads bytes from this file.
 func (f *gcsFile) Read(p []byte) (int, error) {
-	if f.in == nil {
-		var err error
-		f.in, err = f.obj.newReader(f.fsys.Context())
-		if err != nil {
-			return 0, toPathError(err, "Read", f.attrs.Name)
+	if f.seekedToEnd() {
+		return 0, f.eof()
+	}
+	if err := f.openReader(); err != nil {
+		return 0, toPathError(err, "Read", f.attrs.Name)
+	}
+	n, err := f.in.Read(p)
+	f.offset += int64(n)
+	if f.fsys.VerifyChecksum && f.crcValid {
+		f.crc = crc32.Update(f.crc, crc32cTable, p[:n])
+	}
+	if err == io.EOF {
+		err = f.eof()
+	}
+	return n, err
+}
+
+// WriteTo writes the remaining bytes of this file to w. WriteTo continues from
+// the current offset and copies the reader of the object to w directly.
+func (f *gcsFile) WriteTo(w io.Writer) (int64, error) {
+	if f.seekedToEnd() {
+		return 0, ignoreEOF(f.eof())
+	}
+	if err := f.openReader(); err != nil {
+		return 0, toPathError(err, "WriteTo", f.attrs.Name)
+	}
+	verify := f.fsys.VerifyChecksum && f.crcValid
+	if verify {
+		w = io.MultiWriter(w, crcWriter{f: f})
+	}
+	n, err := io.Copy(w, f.in)
+	f.offset += n
+	if err != nil {
+		return n, toPathError(err, "WriteTo", f.attrs.Name)
+	}
+	return n, ignoreEOF(f.eof())
+}
+
+// crcWriter updates the CRC32C of the file by the written bytes.
+type crcWriter struct {
+	f *gcsFile
+}
+
+func (w crcWriter) Write(p []byte) (int, error) {
+	w.f.crc = crc32.Update(w.f.crc, crc32cTable, p)
+	return len(p), nil
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// eof returns io.EOF or ErrChecksumMismatch if VerifyChecksum is enabled and
+// the CRC32C of the sequentially read bytes does not match the object.
+func (f *gcsFile) eof() error {
+	if f.fsys.VerifyChecksum && f.crcValid && f.crc != f.attrs.CRC32C {
+		return toPathError(ErrChecksumMismatch, "Read", f.attrs.Name)
+	}
+	return io.EOF
+}
+
+// Seek sets the offset for the next Read. If the reader has already been opened
+// and the offset changes then the reader is reopened from the new offset.
+func (f *gcsFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.attrs.Size
+	default:
+		return 0, toPathError(fs.ErrInvalid, "Seek", f.attrs.Name)
+	}
+	if offset < 0 {
+		return 0, toPathError(fs.ErrInvalid, "Seek", f.attrs.Name)
+	}
+	if offset != f.offset {
+		if err := f.closeReader(); err != nil {
+			return 0, toPathError(err, "Seek", f.attrs.Name)
 		}
+		f.crc = 0
+		f.crcValid = offset == 0
 	}
-	return f.in.Read(p)
+	f.offset = offset
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes from this file starting at byte offset off.
+// Each call opens an independent range reader, so ReadAt is safe to call
+// concurrently and does not affect the offset used by Read.
+func (f *gcsFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, toPathError(fs.ErrInvalid, "ReadAt", f.attrs.Name)
+	}
+	if off >= f.attrs.Size {
+		return 0, io.EOF
+	}
+	in, err := f.obj.newRangeReader(f.ctx, f.attrs.Generation, off, int64(len(p)))
+	if err != nil {
+		return 0, toPathError(err, "ReadAt", f.attrs.Name)
+	}
+	defer in.Close()
+
+	n, err := io.ReadFull(in, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
 }
 
 // Stat returns the fs.FileInfo of this file.
@@ -48,8 +190,7 @@ func (f *gcsFile) Stat() (fs.FileInfo, error) {
 	return f, nil
 }
 
-// Close closes streams.
-func (f *gcsFile) Close() error {
+func (f *gcsFile) closeReader() error {
 	var err error
 	if f.in != nil {
 		err = f.in.Close()
@@ -58,12 +199,25 @@ func (f *gcsFile) Close() error {
 	return err
 }
 
+// Close closes streams.
+func (f *gcsFile) Close() error {
+	err := f.closeReader()
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+	}
+	return err
+}
+
 type gcsWriterFile struct {
 	*content
-	fsys *GCSFS
-	name string
-	obj  gcsObject
-	out  io.WriteCloser
+	ctx   context.Context
+	fsys  *GCSFS
+	name  string
+	obj   gcsObject
+	attrs *storage.ObjectAttrs
+	conds *storage.Conditions
+	out   io.WriteCloser
 }
 
 var (
@@ -71,31 +225,37 @@ var (
 	_ fs.FileInfo    = (*gcsWriterFile)(nil)
 )
 
-func newGcsWriterFile(fsys *GCSFS, obj gcsObject, name string) *gcsWriterFile {
+func newGcsWriterFile(ctx context.Context, fsys *GCSFS, obj gcsObject, name string, attrs *storage.ObjectAttrs, conds *storage.Conditions) *gcsWriterFile {
 	return &gcsWriterFile{
 		content: &content{
 			name: path.Base(name),
 		},
-		fsys: fsys,
-		obj:  obj,
-		name: name,
+		ctx:   ctx,
+		fsys:  fsys,
+		obj:   obj,
+		name:  name,
+		attrs: attrs,
+		conds: conds,
 	}
 }
 
 // Write writes the specified bytes to this file.
 func (f *gcsWriterFile) Write(p []byte) (int, error) {
 	if f.out == nil {
-		f.out = f.obj.newWriter(f.fsys.Context())
+		f.out = f.obj.newWriter(f.ctx, f.attrs, f.conds)
 	}
-	return f.out.Write(p)
+	n, err := f.out.Write(p)
+	return n, toPreconditionFailedIfFailed(err)
 }
 
-// Close closes streams.
+// Close closes streams. If the preconditions of this file are not satisfied
+// then Close returns ErrPreconditionFailed.
 func (f *gcsWriterFile) Close() error {
 	if f.out != nil {
 		err := f.out.Close()
 		f.out = nil
-		return err
+		f.fsys.invalidateAttrs(f.fsys.key(f.name))
+		return toPreconditionFailedIfFailed(err)
 	}
 	return nil
 }
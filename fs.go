@@ -2,30 +2,74 @@ package gcsfs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"net/url"
 	"path"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/jarxorg/wfs"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 )
 
 const (
-	defaultDirOpenBufferSize = 100
+	defaultDirOpenBufferSize    = 100
+	defaultRemoveAllConcurrency = 8
+)
+
+var (
+	// ErrNotStorageClient is returned by operations that require a *storage.Client
+	// when the filesystem holds another client.
+	ErrNotStorageClient = errors.New("requires *storage.Client")
+	// ErrPreconditionFailed is returned when the conditions specified by
+	// CreateFileWithConditions are not satisfied.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrChecksumMismatch is returned by Read when VerifyChecksum is enabled and
+	// the CRC32C of the read bytes does not match the object.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrInvalidEncryptionKey is returned by all operations when the key specified
+	// by WithEncryptionKey is not a 32-byte AES-256 key.
+	ErrInvalidEncryptionKey = errors.New("encryption key must be 32 bytes")
 )
 
 // GCSFS represents a filesystem on GCS (Google Cloud Storage).
 type GCSFS struct {
 	// DirOpenBufferSize is the buffer size for using objects as the directory. (Default 100)
 	DirOpenBufferSize int
-	bucket            string
-	dir               string
-	ctx               context.Context
-	c                 gcsClient
+	// RemoveAllConcurrency is the number of workers deleting objects in RemoveAll. (Default 8)
+	RemoveAllConcurrency int
+	// VerifyChecksum verifies the CRC32C of the object when a file is read
+	// sequentially from the start to EOF. (Default false)
+	VerifyChecksum bool
+	// CreateDirMarkers creates a zero-byte object named "<dir>/" by MkdirAll so
+	// that empty directories are visible. (Default false)
+	CreateDirMarkers bool
+	// Timeout is the timeout of each Open, Stat, ReadDir, ReadFile and WriteFile. (Default 0: no timeout)
+	Timeout time.Duration
+	// AttrsCacheTTL is the TTL of the cached object attributes used by Open and Stat.
+	// Writes and removes through this filesystem invalidate the cache. Reads are
+	// pinned to the cached generation, so reading an object overwritten by others
+	// within the TTL fails with fs.ErrNotExist instead of returning other bytes.
+	// (Default 0: no cache)
+	AttrsCacheTTL time.Duration
+	bucket        string
+	dir           string
+	userProject   string
+	encryptionKey []byte
+	retryOpts     []storage.RetryOption
+	ctx           context.Context
+	opts          []option.ClientOption
+	c             gcsClient
+	cache         *attrsCache
+	cacheOnce     sync.Once
 }
 
 var (
@@ -42,8 +86,9 @@ var (
 // New returns a filesystem for the tree of objects rooted at the specified bucket.
 func New(bucket string) *GCSFS {
 	return &GCSFS{
-		DirOpenBufferSize: defaultDirOpenBufferSize,
-		bucket:            bucket,
+		DirOpenBufferSize:    defaultDirOpenBufferSize,
+		RemoveAllConcurrency: defaultRemoveAllConcurrency,
+		bucket:               bucket,
 	}
 }
 
@@ -61,12 +106,83 @@ func NewWithClient(bucket string, client *storage.Client) *GCSFS {
 	return New(bucket).WithClient(client)
 }
 
+// NewWithOptions returns a filesystem for the tree of objects rooted at the specified bucket.
+// The specified options are used to create a storage client lazily. The STORAGE_EMULATOR_HOST
+// environment variable is honored by the storage client, so the filesystem can also point
+// at a GCS emulator with option.WithEndpoint or the environment variable.
+//
+//	fsys := gcsfs.NewWithOptions("<your-bucket>", option.WithEndpoint("http://localhost:4443/storage/v1/"))
+//	defer fsys.Close()
+func NewWithOptions(bucket string, opts ...option.ClientOption) *GCSFS {
+	fsys := New(bucket)
+	fsys.opts = opts
+	return fsys
+}
+
+// NewFromURL returns a filesystem for the tree of objects rooted at the bucket and
+// path of the specified gs:// URL.
+//
+//	fsys, err := gcsfs.NewFromURL("gs://<your-bucket>/path/to/dir")
+//	if err != nil {
+//	  log.Fatal(err)
+//	}
+//	data, err := fsys.ReadFile("file.txt") // Reads gs://<your-bucket>/path/to/dir/file.txt
+func NewFromURL(rawurl string) (*GCSFS, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "gs" {
+		return nil, fmt.Errorf("unsupported scheme %q: %s", u.Scheme, rawurl)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no bucket: %s", rawurl)
+	}
+	fsys := New(u.Host)
+	if dir := strings.Trim(u.Path, "/"); dir != "" {
+		if !fs.ValidPath(dir) {
+			return nil, toPathError(fs.ErrInvalid, "NewFromURL", dir)
+		}
+		fsys.dir = dir
+	}
+	return fsys, nil
+}
+
 // WithClient holds the specified client. The specified client is closed by Close.
 func (fsys *GCSFS) WithClient(client *storage.Client) *GCSFS {
 	fsys.c = &storageClient{c: client}
 	return fsys
 }
 
+// WithUserProject holds the specified project ID that is billed for all
+// operations on this filesystem. It is required for requester-pays buckets.
+// An empty project ID disables billing to a user project.
+func (fsys *GCSFS) WithUserProject(projectID string) *GCSFS {
+	fsys.userProject = projectID
+	return fsys
+}
+
+// WithEncryptionKey holds the specified customer-supplied AES-256 key that is
+// used to read, write and copy all objects on this filesystem. If the key is not
+// 32 bytes then all operations return ErrInvalidEncryptionKey.
+func (fsys *GCSFS) WithEncryptionKey(key []byte) *GCSFS {
+	fsys.encryptionKey = key
+	return fsys
+}
+
+// WithRetry holds the specified retry options that are applied to all operations
+// on this filesystem. If no options are specified then the default retry policy
+// of the storage client is used.
+//
+//	fsys := gcsfs.New("<your-bucket>").WithRetry(
+//	  storage.WithBackoff(gax.Backoff{Initial: time.Second, Max: 30 * time.Second}),
+//	  storage.WithPolicy(storage.RetryAlways),
+//	)
+func (fsys *GCSFS) WithRetry(opts ...storage.RetryOption) *GCSFS {
+	fsys.retryOpts = opts
+	return fsys
+}
+
 // WithContext holds the specified context.
 func (fsys *GCSFS) WithContext(ctx context.Context) *GCSFS {
 	fsys.ctx = ctx
@@ -92,9 +208,21 @@ func (fsys *GCSFS) Context() context.Context {
 	return fsys.ctx
 }
 
+// timeoutContext returns fsys.Context() with Timeout. If Timeout is not set then
+// fsys.Context() itself is returned with a no-op cancel.
+func (fsys *GCSFS) timeoutContext() (context.Context, context.CancelFunc) {
+	if fsys.Timeout <= 0 {
+		return fsys.Context(), func() {}
+	}
+	return context.WithTimeout(fsys.Context(), fsys.Timeout)
+}
+
 func (fsys *GCSFS) client() (gcsClient, error) {
+	if fsys.encryptionKey != nil && len(fsys.encryptionKey) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
 	if fsys.c == nil {
-		client, err := storage.NewClient(fsys.Context())
+		client, err := storage.NewClient(fsys.Context(), fsys.opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -103,6 +231,52 @@ func (fsys *GCSFS) client() (gcsClient, error) {
 	return fsys.c, nil
 }
 
+func (fsys *GCSFS) bucketHandle(c gcsClient) gcsBucket {
+	b := c.bucket(fsys.bucket)
+	if fsys.userProject != "" {
+		b = b.userProject(fsys.userProject)
+	}
+	if fsys.encryptionKey != nil {
+		b = b.encryptionKey(fsys.encryptionKey)
+	}
+	if len(fsys.retryOpts) > 0 {
+		b = b.retryer(fsys.retryOpts...)
+	}
+	return b
+}
+
+func (fsys *GCSFS) attrsCache() *attrsCache {
+	fsys.cacheOnce.Do(func() {
+		if fsys.cache == nil {
+			fsys.cache = newAttrsCache()
+		}
+	})
+	return fsys.cache
+}
+
+// objectAttrs returns the attributes of the object. If AttrsCacheTTL is set then
+// the attributes are cached by the key of the object.
+func (fsys *GCSFS) objectAttrs(ctx context.Context, obj gcsObject, key string) (*storage.ObjectAttrs, error) {
+	if fsys.AttrsCacheTTL <= 0 {
+		return obj.attrs(ctx)
+	}
+	cache := fsys.attrsCache()
+	if attrs, ok := cache.get(key); ok {
+		return attrs, nil
+	}
+	attrs, err := obj.attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cache.set(key, attrs, fsys.AttrsCacheTTL)
+	return attrs, nil
+}
+
+// invalidateAttrs removes the cached attributes of the object key.
+func (fsys *GCSFS) invalidateAttrs(key string) {
+	fsys.attrsCache().delete(key)
+}
+
 func (fsys *GCSFS) key(name string) string {
 	return path.Join(fsys.dir, name)
 }
@@ -111,7 +285,7 @@ func (fsys *GCSFS) rel(name string) string {
 	return strings.TrimPrefix(name, normalizePrefix(fsys.dir))
 }
 
-func (fsys *GCSFS) openFile(name string) (*gcsFile, error) {
+func (fsys *GCSFS) openFile(ctx context.Context, name string) (*gcsFile, error) {
 	if !fs.ValidPath(name) {
 		return nil, toPathError(fs.ErrInvalid, "Open", name)
 	}
@@ -120,8 +294,9 @@ func (fsys *GCSFS) openFile(name string) (*gcsFile, error) {
 		return nil, toPathError(err, "Open", name)
 	}
 
-	obj := c.bucket(fsys.bucket).object(fsys.key(name))
-	attrs, err := obj.attrs(fsys.ctx)
+	key := fsys.key(name)
+	obj := fsys.bucketHandle(c).object(key)
+	attrs, err := fsys.objectAttrs(ctx, obj, key)
 	if err != nil {
 		return nil, toPathError(err, "Open", name)
 	}
@@ -129,24 +304,60 @@ func (fsys *GCSFS) openFile(name string) (*gcsFile, error) {
 	if attrs.Name == "" && attrs.Prefix == "" {
 		return nil, toPathError(storage.ErrObjectNotExist, "Open", name)
 	}
-	return newGcsFile(fsys, obj, attrs), nil
+	return newGcsFile(ctx, fsys, obj, attrs), nil
 }
 
 // Open opens the named file or directory.
+// If Timeout is set then the timeout also bounds reading the opened file until Close.
 func (fsys *GCSFS) Open(name string) (fs.File, error) {
-	f, err := fsys.openFile(name)
-	if err != nil && isNotExist(err) {
-		return newGcsDir(fsys, name).open(fsys.DirOpenBufferSize)
+	ctx, cancel := fsys.timeoutContext()
+	f, err := fsys.open(ctx, cancel, name)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	return f, err
+	return f, nil
+}
+
+// OpenContext opens the named file or directory with the specified context.
+// The context is also used by subsequent reads of the opened file.
+func (fsys *GCSFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	return fsys.open(ctx, nil, name)
+}
+
+// open opens the named file or directory. The specified cancel is called when
+// the opened file is closed.
+func (fsys *GCSFS) open(ctx context.Context, cancel context.CancelFunc, name string) (fs.File, error) {
+	f, err := fsys.openFile(ctx, name)
+	if err == nil {
+		f.cancel = cancel
+		return f, nil
+	}
+	if !isNotExist(err) {
+		return nil, err
+	}
+	d, err := newGcsDir(ctx, fsys, name).open(fsys.DirOpenBufferSize)
+	if err != nil {
+		return nil, err
+	}
+	d.cancel = cancel
+	return d, nil
 }
 
 // Stat returns a FileInfo describing the file. If there is an error, it should be
 // of type *PathError.
 func (fsys *GCSFS) Stat(name string) (fs.FileInfo, error) {
-	f, err := fsys.openFile(name)
+	ctx, cancel := fsys.timeoutContext()
+	defer cancel()
+
+	return fsys.StatContext(ctx, name)
+}
+
+// StatContext returns a FileInfo describing the file with the specified context.
+func (fsys *GCSFS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	f, err := fsys.openFile(ctx, name)
 	if err != nil && isNotExist(err) {
-		return newGcsDir(fsys, name).open(1)
+		return newGcsDir(ctx, fsys, name).open(1)
 	}
 	return f, err
 }
@@ -154,15 +365,31 @@ func (fsys *GCSFS) Stat(name string) (fs.FileInfo, error) {
 // ReadDir reads the named directory and returns a list of directory entries
 // sorted by filename.
 func (fsys *GCSFS) ReadDir(dir string) ([]fs.DirEntry, error) {
+	ctx, cancel := fsys.timeoutContext()
+	defer cancel()
+
+	return fsys.ReadDirContext(ctx, dir)
+}
+
+// ReadDirContext reads the named directory with the specified context.
+func (fsys *GCSFS) ReadDirContext(ctx context.Context, dir string) ([]fs.DirEntry, error) {
 	if !fs.ValidPath(dir) {
 		return nil, toPathError(fs.ErrInvalid, "ReadDir", dir)
 	}
-	return newGcsDir(fsys, dir).ReadDir(-1)
+	return newGcsDir(ctx, fsys, dir).ReadDir(-1)
 }
 
 // ReadFile reads the named file and returns its contents.
 func (fsys *GCSFS) ReadFile(name string) ([]byte, error) {
-	f, err := fsys.openFile(name)
+	ctx, cancel := fsys.timeoutContext()
+	defer cancel()
+
+	return fsys.ReadFileContext(ctx, name)
+}
+
+// ReadFileContext reads the named file with the specified context.
+func (fsys *GCSFS) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	f, err := fsys.openFile(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -182,15 +409,27 @@ func (fsys *GCSFS) Sub(dir string) (fs.FS, error) {
 	}
 
 	return &GCSFS{
-		bucket: fsys.bucket,
-		c:      cl,
-		ctx:    fsys.Context(),
-		dir:    path.Join(fsys.dir, dir),
+		DirOpenBufferSize:    fsys.DirOpenBufferSize,
+		RemoveAllConcurrency: fsys.RemoveAllConcurrency,
+		VerifyChecksum:       fsys.VerifyChecksum,
+		CreateDirMarkers:     fsys.CreateDirMarkers,
+		Timeout:              fsys.Timeout,
+		AttrsCacheTTL:        fsys.AttrsCacheTTL,
+		bucket:               fsys.bucket,
+		userProject:          fsys.userProject,
+		encryptionKey:        fsys.encryptionKey,
+		retryOpts:            fsys.retryOpts,
+		c:                    cl,
+		cache:                fsys.attrsCache(),
+		ctx:                  fsys.Context(),
+		dir:                  path.Join(fsys.dir, dir),
 	}, nil
 }
 
 // Glob returns the names of all files matching pattern, providing an implementation
-// of the top-level Glob function.
+// of the top-level Glob function. A "**" path element matches zero or more
+// directories, and such a pattern is resolved by a single listing of the objects
+// under its literal prefix.
 func (fsys *GCSFS) Glob(pattern string) ([]string, error) {
 	if pattern == "" || pattern == "*" {
 		entries, err := fsys.ReadDir("")
@@ -207,7 +446,11 @@ func (fsys *GCSFS) Glob(pattern string) ([]string, error) {
 	if _, err := path.Match(pattern, ""); err != nil {
 		return nil, err
 	}
-	names, err := fsys.glob([]string{""}, strings.Split(pattern, "/"), nil)
+	patterns := strings.Split(pattern, "/")
+	if i := indexOf(patterns, "**"); i != -1 {
+		return fsys.globRecursive(pattern, patterns, i)
+	}
+	names, err := fsys.glob([]string{""}, patterns, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -219,6 +462,53 @@ func (fsys *GCSFS) Glob(pattern string) ([]string, error) {
 	return matches, nil
 }
 
+// globRecursive lists all objects under the literal prefix of patterns[:i] without
+// delimiter and matches the relative keys and their directories against patterns.
+// patterns[i] must be "**".
+func (fsys *GCSFS) globRecursive(pattern string, patterns []string, i int) ([]string, error) {
+	c, err := fsys.client()
+	if err != nil {
+		return nil, err
+	}
+	base := ""
+	if i > 0 {
+		base = strings.Join(patterns[:i], "/") + "/"
+	}
+	query := newQuery("", normalizePrefixPattern(fsys.dir, base), "")
+	it := fsys.bucketHandle(c).objects(fsys.Context(), query)
+
+	seen := map[string]bool{}
+	var matches []string
+	match := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if matchSegments(patterns, strings.Split(name, "/")) {
+			matches = append(matches, name)
+		}
+	}
+	for {
+		attrs, err := it.nextAttrs()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, toPathError(err, "Glob", pattern)
+		}
+		name := strings.TrimSuffix(fsys.rel(attrs.Name), "/")
+		if name == "" {
+			continue
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			match(dir)
+		}
+		match(name)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
 func (fsys *GCSFS) glob(dirs, patterns []string, matches []string) ([]string, error) {
 	dirOnly := len(patterns) > 1
 	var subDirs []string
@@ -246,7 +536,7 @@ func (fsys *GCSFS) listForGlob(pattern string, dirOnly bool) ([]string, error) {
 		return nil, err
 	}
 	query := newQuery("/", normalizePrefixPattern(fsys.dir, pattern), "")
-	it := c.bucket(fsys.bucket).objects(fsys.Context(), query)
+	it := fsys.bucketHandle(c).objects(fsys.Context(), query)
 
 	var names []string
 	for {
@@ -262,7 +552,7 @@ func (fsys *GCSFS) listForGlob(pattern string, dirOnly bool) ([]string, error) {
 			names = appendIfMatch(names, name, pattern)
 			continue
 		}
-		if dirOnly {
+		if dirOnly || isDirMarker(attrs) {
 			continue
 		}
 		name := fsys.rel(attrs.Name)
@@ -271,12 +561,153 @@ func (fsys *GCSFS) listForGlob(pattern string, dirOnly bool) ([]string, error) {
 	return names, nil
 }
 
-// MkdirAll always do nothing.
-func (fsys *GCSFS) MkdirAll(dir string, mode fs.FileMode) error {
+// SignedURL returns a URL for the named file with the specified options.
+// SignedURL works only when this filesystem holds a *storage.Client, otherwise
+// returns a PathError wrapping ErrNotStorageClient.
+func (fsys *GCSFS) SignedURL(name string, opts *storage.SignedURLOptions) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", toPathError(fs.ErrInvalid, "SignedURL", name)
+	}
+	c, err := fsys.client()
+	if err != nil {
+		return "", toPathError(err, "SignedURL", name)
+	}
+	b, ok := fsys.bucketHandle(c).(*storageBucket)
+	if !ok {
+		return "", toPathError(ErrNotStorageClient, "SignedURL", name)
+	}
+	signed, err := b.b.SignedURL(fsys.key(name), opts)
+	if err != nil {
+		return "", toPathError(err, "SignedURL", name)
+	}
+	return signed, nil
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root. WalkDir works like fs.WalkDir but
+// enumerates the whole tree with a single listing without delimiter.
+func (fsys *GCSFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else if !info.IsDir() {
+		err = fn(root, info.(fs.DirEntry), nil)
+	} else {
+		err = fsys.walkDir(root, info.(fs.DirEntry), fn)
+	}
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (fsys *GCSFS) walkDir(root string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(root, d, nil); err != nil {
+		return err
+	}
+	c, err := fsys.client()
+	if err != nil {
+		return fn(root, d, toPathError(err, "WalkDir", root))
+	}
+
+	prefix := normalizePrefix(fsys.key(root))
+	it := fsys.bucketHandle(c).objects(fsys.Context(), newQuery("", prefix, ""))
+	var keys []string
+	attrsMap := map[string]*storage.ObjectAttrs{}
+	for {
+		attrs, err := it.nextAttrs()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fn(root, d, toPathError(err, "WalkDir", root))
+		}
+		key := strings.TrimPrefix(attrs.Name, prefix)
+		if key == "" {
+			continue
+		}
+		keys = append(keys, key)
+		attrsMap[key] = attrs
+	}
+	// NOTE: Sort keys in the order of fs.WalkDir by treating "/" as the lowest character.
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ReplaceAll(keys[i], "/", "\x00") < strings.ReplaceAll(keys[j], "/", "\x00")
+	})
+
+	visited := map[string]bool{}
+	skip := ""
+KEYS:
+	for _, key := range keys {
+		if skip != "" && strings.HasPrefix(key, skip) {
+			continue
+		}
+		isDirKey := strings.HasSuffix(key, "/")
+		elems := strings.Split(strings.TrimSuffix(key, "/"), "/")
+		dirCount := len(elems) - 1
+		if isDirKey {
+			dirCount = len(elems)
+		}
+		for i := 1; i <= dirCount; i++ {
+			dir := strings.Join(elems[:i], "/")
+			if visited[dir] {
+				continue
+			}
+			visited[dir] = true
+			if err := fn(path.Join(root, dir), newDirContent(dir), nil); err != nil {
+				if err == fs.SkipDir {
+					skip = dir + "/"
+					continue KEYS
+				}
+				return err
+			}
+		}
+		if isDirKey {
+			continue
+		}
+		if err := fn(path.Join(root, key), newFileContent(attrsMap[key]), nil); err != nil {
+			if err == fs.SkipDir {
+				dir := path.Dir(key)
+				if dir == "." {
+					return nil
+				}
+				skip = dir + "/"
+				continue
+			}
+			return err
+		}
+	}
 	return nil
 }
 
-func (fsys *GCSFS) createFile(name string) (*gcsWriterFile, error) {
+// MkdirAll does nothing unless CreateDirMarkers is enabled, in which case it
+// writes a zero-byte directory marker object named "<dir>/".
+// The specified mode is ignored.
+func (fsys *GCSFS) MkdirAll(dir string, mode fs.FileMode) error {
+	if !fsys.CreateDirMarkers {
+		return nil
+	}
+	if !fs.ValidPath(dir) {
+		return toPathError(fs.ErrInvalid, "MkdirAll", dir)
+	}
+	prefix := normalizePrefix(fsys.key(dir))
+	if prefix == "" {
+		return nil
+	}
+	c, err := fsys.client()
+	if err != nil {
+		return toPathError(err, "MkdirAll", dir)
+	}
+	for d := dir; d != "."; d = path.Dir(d) {
+		if _, err := fsys.openFile(fsys.Context(), d); err == nil {
+			return toPathError(syscall.ENOTDIR, "MkdirAll", d)
+		}
+	}
+
+	w := fsys.bucketHandle(c).object(prefix).newWriter(fsys.Context(), nil, nil)
+	return toPathError(w.Close(), "MkdirAll", dir)
+}
+
+func (fsys *GCSFS) createFile(ctx context.Context, name string, attrs *storage.ObjectAttrs, conds *storage.Conditions) (*gcsWriterFile, error) {
 	if !fs.ValidPath(name) {
 		return nil, toPathError(fs.ErrInvalid, "Create", name)
 	}
@@ -285,45 +716,185 @@ func (fsys *GCSFS) createFile(name string) (*gcsWriterFile, error) {
 		return nil, toPathError(err, "Create", name)
 	}
 
-	if _, err := fsys.openFile(name); err != nil {
+	if err := fsys.checkWritable(ctx, "CreateFile", name); err != nil {
+		return nil, err
+	}
+
+	obj := fsys.bucketHandle(c).object(fsys.key(name))
+	return newGcsWriterFile(ctx, fsys, obj, name, attrs, conds), nil
+}
+
+// checkWritable returns an error if the named file collides with a directory
+// or its parent is a file.
+func (fsys *GCSFS) checkWritable(ctx context.Context, op, name string) error {
+	if _, err := fsys.openFile(ctx, name); err != nil {
 		if !isNotExist(err) {
-			return nil, toPathError(err, "CreateFile", name)
+			return toPathError(err, op, name)
 		}
-		if _, err := newGcsDir(fsys, name).open(1); err == nil {
-			return nil, toPathError(syscall.EISDIR, "CreateFile", name)
+		if _, err := newGcsDir(ctx, fsys, name).open(1); err == nil {
+			return toPathError(syscall.EISDIR, op, name)
 		}
 	}
 	dir := path.Dir(name)
-	if _, err := fsys.openFile(dir); err == nil {
-		return nil, toPathError(syscall.ENOTDIR, "CreateFile", dir)
+	if _, err := fsys.openFile(ctx, dir); err == nil {
+		return toPathError(syscall.ENOTDIR, op, dir)
 	}
-
-	obj := c.bucket(fsys.bucket).object(fsys.key(name))
-	return newGcsWriterFile(fsys, obj, name), nil
+	return nil
 }
 
 // CreateFile creates the named file.
 // The specified mode is ignored.
 func (fsys *GCSFS) CreateFile(name string, mode fs.FileMode) (wfs.WriterFile, error) {
-	return fsys.createFile(name)
+	return fsys.createFile(fsys.Context(), name, nil, nil)
+}
+
+// CreateFileWithAttrs creates the named file with the specified attributes.
+// ContentType, CacheControl, ContentEncoding and Metadata of attrs are applied
+// to the object. If attrs is nil then GCS detects the content type automatically.
+func (fsys *GCSFS) CreateFileWithAttrs(name string, attrs *storage.ObjectAttrs) (wfs.WriterFile, error) {
+	return fsys.createFile(fsys.Context(), name, attrs, nil)
+}
+
+// CreateFileWithConditions creates the named file with the specified attributes
+// that is written only if the specified conditions are satisfied. For example
+// storage.Conditions{GenerationMatch: gen} writes only if the object is still
+// at the generation, and storage.Conditions{DoesNotExist: true} writes only if
+// the object does not exist. If the conditions are not satisfied then Write or
+// Close of the returned file returns ErrPreconditionFailed. The zero value of
+// storage.Conditions applies no preconditions.
+func (fsys *GCSFS) CreateFileWithConditions(name string, attrs *storage.ObjectAttrs, conds storage.Conditions) (wfs.WriterFile, error) {
+	if conds == (storage.Conditions{}) {
+		return fsys.createFile(fsys.Context(), name, attrs, nil)
+	}
+	return fsys.createFile(fsys.Context(), name, attrs, &conds)
 }
 
 // WriteFile writes the specified bytes to the named file.
 // The specified mode is ignored.
 func (fsys *GCSFS) WriteFile(name string, p []byte, mode fs.FileMode) (int, error) {
-	f, err := fsys.createFile(name)
+	ctx, cancel := fsys.timeoutContext()
+	defer cancel()
+
+	f, err := fsys.createFile(ctx, name, nil, nil)
 	if err != nil {
 		return 0, err
 	}
-	defer f.Close()
 
 	n, err := f.Write(p)
 	if err != nil {
+		f.Close()
+		return 0, toPathError(err, "WriteFile", name)
+	}
+	// NOTE: The upload fails in Close.
+	if err := f.Close(); err != nil {
 		return 0, toPathError(err, "WriteFile", name)
 	}
 	return n, nil
 }
 
+func (fsys *GCSFS) copy(op, dst, src string, srcGen int64) error {
+	if !fs.ValidPath(src) {
+		return toPathError(fs.ErrInvalid, op, src)
+	}
+	if !fs.ValidPath(dst) {
+		return toPathError(fs.ErrInvalid, op, dst)
+	}
+	c, err := fsys.client()
+	if err != nil {
+		return toPathError(err, op, src)
+	}
+
+	if err := fsys.checkWritable(fsys.Context(), op, dst); err != nil {
+		return err
+	}
+
+	b := fsys.bucketHandle(c)
+	dstKey := fsys.key(dst)
+	defer fsys.invalidateAttrs(dstKey)
+	return toPathError(b.copy(fsys.Context(), dstKey, fsys.key(src), srcGen), op, src)
+}
+
+// Copy copies the src file to dst on the server side.
+func (fsys *GCSFS) Copy(dst, src string) error {
+	return fsys.copy("Copy", dst, src, 0)
+}
+
+// Rename copies the src file to dst and then removes src.
+// If the copy fails then src is not removed. If src is replaced while renaming
+// then Rename does not remove src and returns ErrPreconditionFailed. If dst and
+// src are the same file then Rename does nothing.
+func (fsys *GCSFS) Rename(dst, src string) error {
+	if !fs.ValidPath(src) {
+		return toPathError(fs.ErrInvalid, "Rename", src)
+	}
+	if !fs.ValidPath(dst) {
+		return toPathError(fs.ErrInvalid, "Rename", dst)
+	}
+	c, err := fsys.client()
+	if err != nil {
+		return toPathError(err, "Rename", src)
+	}
+
+	key := fsys.key(src)
+	obj := fsys.bucketHandle(c).object(key)
+	if fsys.key(dst) == key {
+		_, err := fsys.objectAttrs(fsys.Context(), obj, key)
+		return toPathError(err, "Rename", src)
+	}
+	attrs, err := obj.attrs(fsys.Context())
+	if err != nil {
+		return toPathError(err, "Rename", src)
+	}
+	if err := fsys.copy("Rename", dst, src, attrs.Generation); err != nil {
+		return err
+	}
+
+	defer fsys.invalidateAttrs(key)
+	// NOTE: Remove src only if it is still the copied generation.
+	var conds *storage.Conditions
+	if attrs.Generation > 0 {
+		conds = &storage.Conditions{GenerationMatch: attrs.Generation}
+	}
+	err = obj.delete(fsys.Context(), conds)
+	return toPathError(toPreconditionFailedIfFailed(err), "Rename", src)
+}
+
+// WriteFiles writes the specified files concurrently by the specified number of
+// workers. The keys of files are the names and the values are the contents.
+// If writing a file fails then the remaining files are not written and the first
+// error is returned.
+func (fsys *GCSFS) WriteFiles(ctx context.Context, files map[string][]byte, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	// NOTE: Create the client before the workers share it.
+	if _, err := fsys.client(); err != nil {
+		return toPathError(err, "WriteFiles", ".")
+	}
+	pool := newWorkerPool(ctx, "WriteFiles", concurrency, func(ctx context.Context, name string) error {
+		return fsys.writeFile(ctx, name, files[name])
+	})
+	for name := range files {
+		if !pool.send(name) {
+			pool.fail(toPathError(pool.ctx.Err(), "WriteFiles", name))
+			break
+		}
+	}
+	return pool.wait()
+}
+
+func (fsys *GCSFS) writeFile(ctx context.Context, name string, p []byte) error {
+	f, err := fsys.createFile(ctx, name, nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(p); err != nil {
+		f.Close()
+		return toPathError(err, "WriteFiles", name)
+	}
+	return toPathError(f.Close(), "WriteFiles", name)
+}
+
 // RemoveFile removes the specified named file.
 func (fsys *GCSFS) RemoveFile(name string) error {
 	if !fs.ValidPath(name) {
@@ -334,37 +905,85 @@ func (fsys *GCSFS) RemoveFile(name string) error {
 		return toPathError(err, "RemoveFile", name)
 	}
 
-	obj := c.bucket(fsys.bucket).object(fsys.key(name))
-	return toPathError(obj.delete(fsys.Context()), "RemoveFile", name)
+	key := fsys.key(name)
+	defer fsys.invalidateAttrs(key)
+	obj := fsys.bucketHandle(c).object(key)
+	return toPathError(obj.delete(fsys.Context(), nil), "RemoveFile", name)
 }
 
 // RemoveAll removes path and any children it contains.
+// The objects are deleted by RemoveAllConcurrency workers. If a deletion fails
+// then the remaining deletions are cancelled and the first error is returned.
+// If path is a file then the file is removed. If neither the file nor objects
+// under path exist then RemoveAll returns a PathError wrapping fs.ErrNotExist.
 func (fsys *GCSFS) RemoveAll(dir string) error {
+	return fsys.removeAll("RemoveAll", dir, false)
+}
+
+// RemoveAllIgnoreNotExist is like RemoveAll but returns nil if no objects exist
+// under path.
+func (fsys *GCSFS) RemoveAllIgnoreNotExist(dir string) error {
+	return fsys.removeAll("RemoveAllIgnoreNotExist", dir, true)
+}
+
+func (fsys *GCSFS) removeAll(op, dir string, ignoreNotExist bool) error {
 	if !fs.ValidPath(dir) {
-		return toPathError(fs.ErrInvalid, "RemoveAll", dir)
+		return toPathError(fs.ErrInvalid, op, dir)
 	}
 	c, err := fsys.client()
 	if err != nil {
-		return toPathError(err, "RemoveAll", dir)
+		return toPathError(err, op, dir)
 	}
 
-	b := c.bucket(fsys.bucket)
-	ctx := fsys.Context()
-	query := newQuery("", normalizePrefix(fsys.key(dir)), "")
-	it := b.objects(fsys.Context(), query)
+	concurrency := fsys.RemoveAllConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultRemoveAllConcurrency
+	}
+	b := fsys.bucketHandle(c)
+	pool := newWorkerPool(fsys.Context(), op, concurrency, func(ctx context.Context, name string) error {
+		defer fsys.invalidateAttrs(name)
+		return toPathError(b.object(name).delete(ctx, nil), op, name)
+	})
+
+	found, err := sendForRemoveAll(pool, b, fsys.key(dir))
+	if err != nil {
+		pool.fail(toPathError(err, op, dir))
+	}
+	if err := pool.wait(); err != nil {
+		return err
+	}
+	if !found && !ignoreNotExist {
+		return toPathError(fs.ErrNotExist, op, dir)
+	}
+	return nil
+}
+
+// sendForRemoveAll sends the object of the key itself and the objects under the
+// key to the pool. sendForRemoveAll reports whether any object was found.
+func sendForRemoveAll(pool *workerPool, b gcsBucket, key string) (bool, error) {
+	found := false
+	if _, err := b.object(key).attrs(pool.ctx); err == nil {
+		found = true
+		if !pool.send(key) {
+			return found, pool.ctx.Err()
+		}
+	} else if !isObjectNotExist(err) {
+		return found, err
+	}
+
+	query := newQuery("", normalizePrefix(key), "")
+	it := b.objects(pool.ctx, query)
 	for {
 		attrs, err := it.nextAttrs()
 		if err == iterator.Done {
-			break
+			return found, nil
 		}
 		if err != nil {
-			return toPathError(err, "RemoveAll", dir)
+			return found, err
 		}
-		name := path.Join(attrs.Prefix, attrs.Name)
-		obj := b.object(name)
-		if err := obj.delete(ctx); err != nil {
-			return toPathError(err, "RemoveAll", name)
+		found = true
+		if !pool.send(attrs.Name) {
+			return found, pool.ctx.Err()
 		}
 	}
-	return nil
 }
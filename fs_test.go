@@ -1,17 +1,24 @@
 package gcsfs
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/jarxorg/io2"
@@ -19,6 +26,7 @@ import (
 	"github.com/jarxorg/wfs/memfs"
 	"github.com/jarxorg/wfs/osfs"
 	"github.com/jarxorg/wfs/wfstest"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
@@ -46,7 +54,28 @@ func (b *fsBucket) object(name string) gcsObject {
 }
 
 func (b *fsBucket) objects(ctx context.Context, q *storage.Query) gcsObjectItetator {
-	return &fsObjects{fsys: b.fsys, dir: b.dir, query: q}
+	return &fsObjects{ctx: ctx, fsys: b.fsys, dir: b.dir, query: q}
+}
+
+func (b *fsBucket) copy(ctx context.Context, dst, src string, srcGen int64) error {
+	p, err := (&fsObject{fsys: b.fsys, dir: b.dir, name: src}).readGeneration(srcGen)
+	if err != nil {
+		return err
+	}
+	_, err = wfs.WriteFile(b.fsys, path.Join(b.dir, dst), p, fs.ModePerm)
+	return err
+}
+
+func (b *fsBucket) userProject(projectID string) gcsBucket {
+	return b
+}
+
+func (b *fsBucket) encryptionKey(key []byte) gcsBucket {
+	return b
+}
+
+func (b *fsBucket) retryer(opts ...storage.RetryOption) gcsBucket {
+	return b
 }
 
 type fsObject struct {
@@ -55,7 +84,37 @@ type fsObject struct {
 	name string
 }
 
-func (o *fsObject) newReader(ctx context.Context) (io.ReadCloser, error) {
+// fsGeneration returns the generation of the content. An overwrite with
+// different bytes generates a new generation.
+func fsGeneration(p []byte) int64 {
+	return int64(crc32.Checksum(p, crc32cTable)) + 1
+}
+
+// readGeneration reads the object of the specified generation. If gen is not 0
+// and the object has been overwritten then readGeneration returns
+// storage.ErrObjectNotExist like a bucket without versioning.
+func (o *fsObject) readGeneration(gen int64) ([]byte, error) {
+	b, err := fs.ReadFile(o.fsys, path.Join(o.dir, o.name))
+	if err != nil {
+		return nil, toObjectNotExistIfNoExist(err)
+	}
+	if gen != 0 && gen != fsGeneration(b) {
+		return nil, storage.ErrObjectNotExist
+	}
+	return b, nil
+}
+
+func (o *fsObject) newReader(ctx context.Context, gen int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if gen != 0 {
+		b, err := o.readGeneration(gen)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
 	in, err := o.fsys.Open(path.Join(o.dir, o.name))
 	if err != nil {
 		return nil, toObjectNotExistIfNoExist(err)
@@ -63,7 +122,49 @@ func (o *fsObject) newReader(ctx context.Context) (io.ReadCloser, error) {
 	return in, nil
 }
 
-func (o *fsObject) newWriter(ctx context.Context) io.WriteCloser {
+func (o *fsObject) newRangeReader(ctx context.Context, gen, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b, err := o.readGeneration(gen)
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	b = b[offset:]
+	if length >= 0 && length < int64(len(b)) {
+		b = b[:length]
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (o *fsObject) newWriter(ctx context.Context, attrs *storage.ObjectAttrs, conds *storage.Conditions) io.WriteCloser {
+	if conds != nil && conds.DoesNotExist {
+		if _, err := fs.Stat(o.fsys, path.Join(o.dir, o.name)); err == nil {
+			err := &googleapi.Error{Code: http.StatusPreconditionFailed}
+			return &io2.Delegator{
+				WriteFunc: func(p []byte) (int, error) {
+					return 0, err
+				},
+				CloseFunc: func() error {
+					return err
+				},
+			}
+		}
+	}
+	if strings.HasSuffix(o.name, "/") {
+		err := wfs.MkdirAll(o.fsys, path.Join(o.dir, o.name), fs.ModePerm)
+		return &io2.Delegator{
+			WriteFunc: func(p []byte) (int, error) {
+				return 0, err
+			},
+			CloseFunc: func() error {
+				return err
+			},
+		}
+	}
 	f, createErr := wfs.CreateFile(o.fsys, path.Join(o.dir, o.name), fs.ModePerm)
 
 	return &io2.Delegator{
@@ -83,6 +184,9 @@ func (o *fsObject) newWriter(ctx context.Context) io.WriteCloser {
 }
 
 func (o *fsObject) attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	info, err := fs.Stat(o.fsys, path.Join(o.dir, o.name))
 	if err != nil {
 		return nil, toObjectNotExistIfNoExist(err)
@@ -90,19 +194,35 @@ func (o *fsObject) attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
 	if info.IsDir() {
 		return nil, storage.ErrObjectNotExist
 	}
+	p, err := fs.ReadFile(o.fsys, path.Join(o.dir, o.name))
+	if err != nil {
+		return nil, toObjectNotExistIfNoExist(err)
+	}
 	return &storage.ObjectAttrs{
-		Bucket:  o.dir,
-		Name:    o.name,
-		Size:    info.Size(),
-		Updated: info.ModTime(),
+		Bucket:     o.dir,
+		Name:       o.name,
+		Size:       info.Size(),
+		Updated:    info.ModTime(),
+		CRC32C:     crc32.Checksum(p, crc32cTable),
+		Generation: fsGeneration(p),
 	}, nil
 }
 
-func (o *fsObject) delete(ctx context.Context) error {
-	return wfs.RemoveFile(o.fsys, path.Join(o.dir, o.name))
+func (o *fsObject) delete(ctx context.Context, conds *storage.Conditions) error {
+	name := path.Join(o.dir, o.name)
+	if _, err := fs.Stat(o.fsys, name); errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrObjectNotExist
+	}
+	if conds != nil && conds.GenerationMatch != 0 {
+		if _, err := o.readGeneration(conds.GenerationMatch); err != nil {
+			return &googleapi.Error{Code: http.StatusPreconditionFailed}
+		}
+	}
+	return wfs.RemoveFile(o.fsys, name)
 }
 
 type fsObjects struct {
+	ctx       context.Context
 	fsys      fs.FS
 	dir       string
 	query     *storage.Query
@@ -150,6 +270,9 @@ func (o *fsObjects) readDir() error {
 
 	ds, err := fs.ReadDir(o.fsys, path.Join(o.dir, prefix))
 	if err != nil {
+		if isNotExist(err) {
+			return nil
+		}
 		return toObjectNotExistIfNoExist(err)
 	}
 	for _, d := range ds {
@@ -199,6 +322,9 @@ func (o *fsObjects) walkDir() error {
 
 	return fs.WalkDir(o.fsys, root, func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
+			if name == root && isNotExist(err) {
+				return nil
+			}
 			return err
 		}
 		if name == root || !strings.HasPrefix(name, namePrefix) {
@@ -239,6 +365,9 @@ func (o *fsObjects) walkDir() error {
 }
 
 func (o *fsObjects) nextAttrs() (*storage.ObjectAttrs, error) {
+	if err := o.ctx.Err(); err != nil {
+		return nil, err
+	}
 	if err := o.initAttrs(); err != nil {
 		return nil, err
 	}
@@ -262,15 +391,1163 @@ func TestFS(t *testing.T) {
 }
 
 func TestWriteFileFS(t *testing.T) {
+	for _, createDirMarkers := range []bool{false, true} {
+		fsys := &GCSFS{
+			CreateDirMarkers: createDirMarkers,
+			bucket:           "testdata",
+			c:                &fsClient{fsys: memfs.New()},
+		}
+		tmpDir := "test"
+		if err := wfs.MkdirAll(fsys, tmpDir, fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := wfstest.TestWriteFileFS(fsys, tmpDir); err != nil {
+			t.Errorf("Error wfstest with CreateDirMarkers %v: %+v", createDirMarkers, err)
+		}
+	}
+}
+
+func TestMkdirAllUnderFile(t *testing.T) {
+	fsys := &GCSFS{
+		CreateDirMarkers: true,
+		bucket:           "testdata",
+		c:                &fsClient{fsys: memfs.New()},
+	}
+	if _, err := fsys.WriteFile("dir/file.txt", []byte("test"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		path string
+	}{
+		{dir: "dir/file.txt", path: "dir/file.txt"},
+		{dir: "dir/file.txt/sub", path: "dir/file.txt"},
+		{dir: "dir/file.txt/sub/sub", path: "dir/file.txt"},
+	}
+	for _, test := range tests {
+		err := fsys.MkdirAll(test.dir, fs.ModePerm)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Err != syscall.ENOTDIR || pathErr.Path != test.path {
+			t.Errorf(`Error MkdirAll(%s) returns %v; want %v of %s`, test.dir, err, syscall.ENOTDIR, test.path)
+		}
+	}
+	if err := fsys.MkdirAll("dir/sub/sub", fs.ModePerm); err != nil {
+		t.Errorf(`Error MkdirAll(dir/sub/sub) returns %v; want nil`, err)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+	f, err := fsys.Open("dir0/file01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s, ok := f.(io.ReadSeeker)
+	if !ok {
+		t.Fatalf("Error %T does not implement io.ReadSeeker", f)
+	}
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   string
+	}{
+		{
+			offset: 4,
+			whence: io.SeekStart,
+			want:   "ent01\n",
+		}, {
+			offset: -3,
+			whence: io.SeekEnd,
+			want:   "01\n",
+		}, {
+			offset: -7,
+			whence: io.SeekCurrent,
+			want:   "tent01\n",
+		},
+	}
+	for _, test := range tests {
+		if _, err := s.Seek(test.offset, test.whence); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf(`Error Seek(%d, %d) then read %q; want %q`, test.offset, test.whence, got, test.want)
+		}
+	}
+
+	if _, err := s.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf(`Error Seek(-1, io.SeekStart) returns %v; want %v`, err, fs.ErrInvalid)
+	}
+}
+
+func TestReadOverwritten(t *testing.T) {
+	mfs := memfs.New()
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: mfs},
+	}
+	if _, err := fsys.WriteFile("test.txt", []byte("before"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Open("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := wfs.WriteFile(mfs, "testdata/test.txt", []byte("after overwritten"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(f); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error ReadAll after overwritten returns %q, %v; want %v`, got, err, fs.ErrNotExist)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+	f, err := fsys.Open("dir0/file01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatalf("Error %T does not implement io.ReaderAt", f)
+	}
+
+	tests := []struct {
+		off     int64
+		size    int
+		want    string
+		wantErr error
+	}{
+		{
+			off:  0,
+			size: 7,
+			want: "content",
+		}, {
+			off:  7,
+			size: 2,
+			want: "01",
+		}, {
+			off:     7,
+			size:    5,
+			want:    "01\n",
+			wantErr: io.EOF,
+		}, {
+			off:     10,
+			size:    1,
+			want:    "",
+			wantErr: io.EOF,
+		},
+	}
+	for _, test := range tests {
+		p := make([]byte, test.size)
+		n, err := r.ReadAt(p, test.off)
+		if err != test.wantErr {
+			t.Errorf(`Error ReadAt(%d) returns error %v; want %v`, test.off, err, test.wantErr)
+		}
+		if got := string(p[:n]); got != test.want {
+			t.Errorf(`Error ReadAt(%d) read %q; want %q`, test.off, got, test.want)
+		}
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{
+			dir:  ".",
+			want: []string{"dir0", "file0.txt", "file1.txt", "file2.txt"},
+		}, {
+			dir:  "dir0",
+			want: []string{"file01.txt", "file02.txt", "file03.txt"},
+		},
+	}
+	for _, test := range tests {
+		entries, err := fsys.ReadDir(test.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Name())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf(`Error ReadDir(%s) returns %v; want %v`, test.dir, got, test.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := fsys.OpenContext(ctx, "file0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cancel()
+
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error Read after cancel returns %v; want %v`, err, context.Canceled)
+	}
+	if _, err := fsys.StatContext(ctx, "file0.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error StatContext returns %v; want %v`, err, context.Canceled)
+	}
+	if _, err := fsys.ReadFileContext(ctx, "file0.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error ReadFileContext returns %v; want %v`, err, context.Canceled)
+	}
+	if _, err := fsys.ReadDirContext(ctx, "dir0"); !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error ReadDirContext returns %v; want %v`, err, context.Canceled)
+	}
+
+	if _, err := fsys.ReadFile("file0.txt"); err != nil {
+		t.Errorf(`Error ReadFile returns %v`, err)
+	}
+}
+
+type deleteErrClient struct {
+	gcsClient
+	name string
+	err  error
+}
+
+func (c *deleteErrClient) bucket(name string) gcsBucket {
+	return &deleteErrBucket{gcsBucket: c.gcsClient.bucket(name), name: c.name, err: c.err}
+}
+
+type deleteErrBucket struct {
+	gcsBucket
+	name string
+	err  error
+}
+
+func (b *deleteErrBucket) object(name string) gcsObject {
+	obj := b.gcsBucket.object(name)
+	if name == b.name {
+		return &deleteErrObject{gcsObject: obj, err: b.err}
+	}
+	return obj
+}
+
+type deleteErrObject struct {
+	gcsObject
+	err error
+}
+
+func (o *deleteErrObject) delete(ctx context.Context, conds *storage.Conditions) error {
+	return o.err
+}
+
+func TestRemoveAll(t *testing.T) {
+	mfs := memfs.New()
+	fsys := &GCSFS{
+		RemoveAllConcurrency: 4,
+		bucket:               "testdata",
+		c:                    &fsClient{fsys: mfs},
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("dir/sub%d/file%d.txt", i%3, i)
+		if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := fsys.WriteFile("keep.txt", []byte("keep"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err := fs.WalkDir(mfs, "testdata", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			got = append(got, name)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"testdata/keep.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`Error remaining files %v; want %v`, got, want)
+	}
+}
+
+func TestRemoveAllError(t *testing.T) {
+	mfs := memfs.New()
+	wantErr := errors.New("test")
+	failName := "dir/file3.txt"
+	fsys := &GCSFS{
+		RemoveAllConcurrency: 2,
+		bucket:               "testdata",
+		c: &deleteErrClient{
+			gcsClient: &fsClient{fsys: mfs},
+			name:      failName,
+			err:       wantErr,
+		},
+	}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("dir/file%d.txt", i)
+		if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := fsys.RemoveAll("dir")
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf(`Error RemoveAll returns %v; want *fs.PathError`, err)
+	}
+	if pathErr.Path != failName || pathErr.Err != wantErr {
+		t.Errorf(`Error RemoveAll returns %v; want error of %s`, err, failName)
+	}
+}
+
+func TestRemoveAllCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys := &GCSFS{
+		RemoveAllConcurrency: 2,
+		bucket:               "testdata",
+		c:                    &fsClient{fsys: memfs.New()},
+	}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("dir/file%d.txt", i)
+		if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+
+	err := fsys.WithContext(ctx).RemoveAll("dir")
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error RemoveAll returns %v; want *fs.PathError of %v`, err, context.Canceled)
+	}
+}
+
+func TestRemoveNotExist(t *testing.T) {
 	fsys := &GCSFS{
 		bucket: "testdata",
 		c:      &fsClient{fsys: memfs.New()},
 	}
-	tmpDir := "test"
-	if err := wfs.MkdirAll(fsys, tmpDir, fs.ModePerm); err != nil {
+	if _, err := fsys.WriteFile("dir/file.txt", []byte("file"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		op     string
+		remove func(name string) error
+		name   string
+	}{
+		{
+			op:     "RemoveFile",
+			remove: fsys.RemoveFile,
+			name:   "dir/missing.txt",
+		}, {
+			op:     "RemoveAll",
+			remove: fsys.RemoveAll,
+			name:   "missing",
+		},
+	}
+	for _, test := range tests {
+		err := test.remove(test.name)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf(`Error %s returns %v; want *fs.PathError of %v`, test.op, err, fs.ErrNotExist)
+		}
+		if pathErr.Op != test.op || pathErr.Path != test.name {
+			t.Errorf(`Error %s returns %v; want op %s path %s`, test.op, err, test.op, test.name)
+		}
+	}
+
+	if err := fsys.RemoveAll("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("dir/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Stat after RemoveAll of the file returns %v; want %v`, err, fs.ErrNotExist)
+	}
+	if _, err := fsys.WriteFile("dir/file.txt", []byte("file"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.RemoveAllIgnoreNotExist("missing"); err != nil {
+		t.Errorf(`Error RemoveAllIgnoreNotExist returns %v; want nil`, err)
+	}
+	if err := fsys.RemoveAllIgnoreNotExist("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("dir/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Stat after RemoveAllIgnoreNotExist returns %v; want %v`, err, fs.ErrNotExist)
+	}
+}
+
+func TestCopyAndRename(t *testing.T) {
+	mfs := memfs.New()
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: mfs},
+	}
+	want := []byte("test")
+	if _, err := fsys.WriteFile("src.txt", want, fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.Copy("dir/copy.txt", "src.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename("dir/rename.txt", "src.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir/copy.txt", "dir/rename.txt"} {
+		got, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf(`Error ReadFile(%s) returns %s; want %s`, name, got, want)
+		}
+	}
+	if _, err := fsys.Stat("src.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Stat(src.txt) after Rename returns %v; want %v`, err, fs.ErrNotExist)
+	}
+
+	if err := fsys.Rename("dir/copy.txt", "dir/copy.txt"); err != nil {
 		t.Fatal(err)
 	}
-	if err := wfstest.TestWriteFileFS(fsys, tmpDir); err != nil {
-		t.Errorf("Error wfstest: %+v", err)
+	if got, err := fsys.ReadFile("dir/copy.txt"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf(`Error ReadFile(dir/copy.txt) after Rename to itself returns %s, %v; want %s`, got, err, want)
+	}
+	if err := fsys.Rename("not-found.txt", "not-found.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Rename to itself returns %v; want %v`, err, fs.ErrNotExist)
+	}
+
+	if err := fsys.Copy("dst.txt", "not-found.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Copy returns %v; want %v`, err, fs.ErrNotExist)
+	}
+	if err := fsys.Copy("dst.txt", "../invalid.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf(`Error Copy returns %v; want %v`, err, fs.ErrInvalid)
+	}
+	if err := fsys.Copy("dir", "dir/copy.txt"); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf(`Error Copy to a directory returns %v; want %v`, err, syscall.EISDIR)
+	}
+	if err := fsys.Rename("dir/copy.txt/dst.txt", "dir/rename.txt"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf(`Error Rename under a file returns %v; want %v`, err, syscall.ENOTDIR)
+	}
+	if _, err := fsys.Stat("dir/rename.txt"); err != nil {
+		t.Errorf(`Error Stat(dir/rename.txt) after failed Rename returns %v; want nil`, err)
+	}
+}
+
+type replaceAfterCopyClient struct {
+	gcsClient
+	p []byte
+}
+
+func (c *replaceAfterCopyClient) bucket(name string) gcsBucket {
+	return &replaceAfterCopyBucket{gcsBucket: c.gcsClient.bucket(name), p: c.p}
+}
+
+type replaceAfterCopyBucket struct {
+	gcsBucket
+	p []byte
+}
+
+func (b *replaceAfterCopyBucket) copy(ctx context.Context, dst, src string, srcGen int64) error {
+	if err := b.gcsBucket.copy(ctx, dst, src, srcGen); err != nil {
+		return err
+	}
+	w := b.gcsBucket.object(src).newWriter(ctx, nil, nil)
+	if _, err := w.Write(b.p); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func TestRenameReplaced(t *testing.T) {
+	want := []byte("replaced")
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &replaceAfterCopyClient{gcsClient: &fsClient{fsys: memfs.New()}, p: want},
+	}
+	if _, err := fsys.WriteFile("src.txt", []byte("test"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.Rename("dst.txt", "src.txt"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf(`Error Rename returns %v; want %v`, err, ErrPreconditionFailed)
+	}
+	got, err := fsys.ReadFile("src.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(`Error ReadFile(src.txt) returns %s; want %s`, got, want)
+	}
+}
+
+type countClient struct {
+	gcsClient
+	objectsCount int
+	attrsCount   int
+	readersCount int
+}
+
+func (c *countClient) bucket(name string) gcsBucket {
+	return &countBucket{gcsBucket: c.gcsClient.bucket(name), c: c}
+}
+
+type countBucket struct {
+	gcsBucket
+	c *countClient
+}
+
+func (b *countBucket) objects(ctx context.Context, q *storage.Query) gcsObjectItetator {
+	b.c.objectsCount++
+	return b.gcsBucket.objects(ctx, q)
+}
+
+func (b *countBucket) object(name string) gcsObject {
+	return &countObject{gcsObject: b.gcsBucket.object(name), c: b.c}
+}
+
+type countObject struct {
+	gcsObject
+	c *countClient
+}
+
+func (o *countObject) newReader(ctx context.Context, gen int64) (io.ReadCloser, error) {
+	o.c.readersCount++
+	return o.gcsObject.newReader(ctx, gen)
+}
+
+func (o *countObject) newRangeReader(ctx context.Context, gen, offset, length int64) (io.ReadCloser, error) {
+	o.c.readersCount++
+	return o.gcsObject.newRangeReader(ctx, gen, offset, length)
+}
+
+func (o *countObject) attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	o.c.attrsCount++
+	return o.gcsObject.attrs(ctx)
+}
+
+type walkResult struct {
+	name  string
+	isDir bool
+}
+
+func walkResults(t *testing.T, walk func(root string, fn fs.WalkDirFunc) error, root string, skip string) []walkResult {
+	var results []walkResult
+	err := walk(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		results = append(results, walkResult{name: name, isDir: d.IsDir()})
+		if name == skip {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestWalkDir(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(t.TempDir())},
+	}
+	for _, name := range []string{
+		"a.txt",
+		"a/b.txt",
+		"a/c/d.txt",
+		"a-b/x.txt",
+		"z/y.txt",
+		"z/z.txt",
+	} {
+		if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		root string
+		skip string
+	}{
+		{root: "."},
+		{root: "a"},
+		{root: "a.txt"},
+		{root: ".", skip: "a"},
+		{root: ".", skip: "a/b.txt"},
+		{root: ".", skip: "a-b/x.txt"},
+		{root: ".", skip: "a.txt"},
+		{root: ".", skip: "."},
+	}
+	for _, test := range tests {
+		c := &countClient{gcsClient: fsys.c}
+		fsys.c = c
+		got := walkResults(t, fsys.WalkDir, test.root, test.skip)
+		fsys.c = c.gcsClient
+
+		want := walkResults(t, func(root string, fn fs.WalkDirFunc) error {
+			return fs.WalkDir(fsys, root, fn)
+		}, test.root, test.skip)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf(`Error WalkDir(%s) skip %s returns %v; want %v`, test.root, test.skip, got, want)
+		}
+		if c.objectsCount > 2 {
+			t.Errorf(`Error WalkDir(%s) lists objects %d times`, test.root, c.objectsCount)
+		}
+	}
+}
+
+func TestWalkDirNotExist(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+	err := fsys.WalkDir("not-found", func(name string, d fs.DirEntry, err error) error {
+		return err
+	})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error WalkDir returns %v; want %v`, err, fs.ErrNotExist)
+	}
+}
+
+func TestCreateFileWithConditions(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: memfs.New()},
+	}
+	conds := storage.Conditions{DoesNotExist: true}
+
+	f, err := fsys.CreateFileWithConditions("test.txt", nil, conds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("test")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = fsys.CreateFileWithConditions("test.txt", nil, conds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("overwrite")); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf(`Error Write returns %v; want %v`, err, ErrPreconditionFailed)
+	}
+	if err := f.Close(); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf(`Error Close returns %v; want %v`, err, ErrPreconditionFailed)
+	}
+
+	got, err := fsys.ReadFile("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "test" {
+		t.Errorf(`Error ReadFile returns %s; want test`, got)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	fsys := &GCSFS{
+		VerifyChecksum: true,
+		bucket:         "testdata",
+		c:              &fsClient{fsys: osfs.New(".")},
+	}
+	if _, err := fsys.ReadFile("dir0/file01.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.openFile(fsys.Context(), "dir0/file01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.attrs.CRC32C++
+
+	if _, err := io.ReadAll(f); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf(`Error ReadAll returns %v; want %v`, err, ErrChecksumMismatch)
+	}
+
+	if _, err := f.Seek(3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(f); err != nil {
+		t.Errorf(`Error ReadAll after Seek returns %v; want nil`, err)
+	}
+}
+
+func TestStatDirGeneration(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+	info, err := fsys.Stat("dir0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi, ok := info.(GCSFileInfo)
+	if !ok {
+		t.Fatalf(`Error Stat returns %T; want GCSFileInfo`, info)
+	}
+	if gi.Generation() != 0 || gi.Metageneration() != 0 {
+		t.Errorf(`Error directory generations (%d, %d); want (0, 0)`, gi.Generation(), gi.Metageneration())
+	}
+}
+
+func TestNewFromURL(t *testing.T) {
+	tests := []struct {
+		rawurl     string
+		wantBucket string
+		wantDir    string
+		wantErr    bool
+	}{
+		{
+			rawurl:     "gs://bucket",
+			wantBucket: "bucket",
+		}, {
+			rawurl:     "gs://bucket/",
+			wantBucket: "bucket",
+		}, {
+			rawurl:     "gs://bucket/path/to/dir/",
+			wantBucket: "bucket",
+			wantDir:    "path/to/dir",
+		}, {
+			rawurl:  "s3://bucket/path",
+			wantErr: true,
+		}, {
+			rawurl:  "gs:///path",
+			wantErr: true,
+		}, {
+			rawurl:  "gs://bucket/path/../dir",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		fsys, err := NewFromURL(test.rawurl)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf(`Error NewFromURL(%s) returns no error`, test.rawurl)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fsys.bucket != test.wantBucket || fsys.dir != test.wantDir {
+			t.Errorf(`Error NewFromURL(%s) returns bucket %q dir %q; want %q %q`,
+				test.rawurl, fsys.bucket, fsys.dir, test.wantBucket, test.wantDir)
+		}
+	}
+
+	fsys, err := NewFromURL("gs://testdata/dir0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.c = &fsClient{fsys: osfs.New(".")}
+	got, err := fsys.ReadFile("file01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "content01\n"; string(got) != want {
+		t.Errorf(`Error ReadFile returns %q; want %q`, got, want)
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: memfs.New()},
+	}
+	files := map[string][]byte{}
+	for i := 0; i < 30; i++ {
+		name := fmt.Sprintf("dir%d/file%d.txt", i%3, i)
+		files[name] = []byte(name)
+	}
+	if err := fsys.WriteFiles(context.Background(), files, 4); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		got, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf(`Error ReadFile(%s) returns %s; want %s`, name, got, want)
+		}
+	}
+}
+
+func TestWriteFilesError(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: memfs.New()},
+	}
+	if _, err := fsys.WriteFile("dir/file.txt", []byte("test"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"file0.txt": []byte("test"),
+		"dir":       []byte("test"),
+		"file1.txt": []byte("test"),
+	}
+
+	err := fsys.WriteFiles(context.Background(), files, 2)
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf(`Error WriteFiles returns %v; want *fs.PathError`, err)
+	}
+	if pathErr.Path != "dir" || pathErr.Err != syscall.EISDIR {
+		t.Errorf(`Error WriteFiles returns %v; want error of dir`, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = fsys.WriteFiles(ctx, map[string][]byte{"file2.txt": []byte("test")}, 1)
+	if !errors.As(err, &pathErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf(`Error WriteFiles returns %v; want *fs.PathError of %v`, err, context.Canceled)
+	}
+}
+
+type cancelWriterClient struct {
+	gcsClient
+	cancel context.CancelFunc
+}
+
+func (c *cancelWriterClient) bucket(name string) gcsBucket {
+	return &cancelWriterBucket{gcsBucket: c.gcsClient.bucket(name), cancel: c.cancel}
+}
+
+type cancelWriterBucket struct {
+	gcsBucket
+	cancel context.CancelFunc
+}
+
+func (b *cancelWriterBucket) object(name string) gcsObject {
+	return &cancelWriterObject{gcsObject: b.gcsBucket.object(name), cancel: b.cancel}
+}
+
+type cancelWriterObject struct {
+	gcsObject
+	cancel context.CancelFunc
+}
+
+func (o *cancelWriterObject) newWriter(ctx context.Context, attrs *storage.ObjectAttrs, conds *storage.Conditions) io.WriteCloser {
+	o.cancel()
+	return o.gcsObject.newWriter(ctx, attrs, conds)
+}
+
+func TestWriteFilesCancel(t *testing.T) {
+	files := map[string][]byte{}
+	for i := 0; i < 100; i++ {
+		files[fmt.Sprintf("file%d.txt", i)] = []byte("test")
+	}
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		fsys := &GCSFS{
+			bucket: "testdata",
+			c:      &cancelWriterClient{gcsClient: &fsClient{fsys: memfs.New()}, cancel: cancel},
+		}
+		err := fsys.WriteFiles(ctx, files, 4)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || !errors.Is(err, context.Canceled) {
+			t.Fatalf(`Error WriteFiles returns %v; want *fs.PathError of %v`, err, context.Canceled)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fsys := &GCSFS{
+		Timeout: time.Nanosecond,
+		bucket:  "testdata",
+		c:       &fsClient{fsys: osfs.New(".")},
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := fsys.Stat("file0.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`Error Stat returns %v; want %v`, err, context.DeadlineExceeded)
+	}
+	if _, err := fsys.ReadDir("dir0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`Error ReadDir returns %v; want %v`, err, context.DeadlineExceeded)
+	}
+
+	fsys.Timeout = time.Minute
+	f, err := fsys.Open("file0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "content0\n"; string(got) != want {
+		t.Errorf(`Error ReadAll returns %q; want %q`, got, want)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.(*gcsFile).ctx.Err(); err != context.Canceled {
+		t.Errorf(`Error the context of the closed file is %v; want %v`, err, context.Canceled)
+	}
+
+	fsys.Timeout = 0
+	f, err = fsys.Open("file0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := f.(*gcsFile).ctx; got != fsys.Context() {
+		t.Errorf(`Error the context of the file without Timeout is %v; want %v`, got, fsys.Context())
+	}
+}
+
+func TestAttrsCacheTTL(t *testing.T) {
+	c := &countClient{gcsClient: &fsClient{fsys: memfs.New()}}
+	fsys := &GCSFS{
+		AttrsCacheTTL: time.Hour,
+		bucket:        "testdata",
+		c:             c,
+	}
+	if _, err := fsys.WriteFile("test.txt", []byte("test"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	c.attrsCount = 0
+	info, err := fsys.Stat("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadFile("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if c.attrsCount != 1 {
+		t.Errorf(`Error Stat and ReadFile get attrs %d times; want 1`, c.attrsCount)
+	}
+
+	if _, err := fsys.WriteFile("test.txt", []byte("updated"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := fsys.Stat("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Size() == info.Size() {
+		t.Errorf(`Error Stat after WriteFile returns stale size %d`, updated.Size())
+	}
+
+	if err := fsys.RemoveFile("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("test.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Stat after RemoveFile returns %v; want %v`, err, fs.ErrNotExist)
+	}
+}
+
+func TestAttrsCacheTTLConcurrent(t *testing.T) {
+	fsys := &GCSFS{
+		AttrsCacheTTL: time.Hour,
+		bucket:        "testdata",
+		c:             &fsClient{fsys: osfs.New(t.TempDir())},
+	}
+	if _, err := fsys.WriteFile("shared.txt", []byte("shared"), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("file%d.txt", i)
+			for j := 0; j < 10; j++ {
+				if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+					errs <- err
+					return
+				}
+				for _, n := range []string{name, "shared.txt"} {
+					if _, err := fsys.Stat(n); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestAttrsCacheTTLOverwritten(t *testing.T) {
+	mfs := memfs.New()
+	fsys := &GCSFS{
+		AttrsCacheTTL:  time.Hour,
+		VerifyChecksum: true,
+		bucket:         "testdata",
+		c:              &fsClient{fsys: mfs},
+	}
+	if _, err := fsys.WriteFile("test.txt", bytes.Repeat([]byte("a"), 5000), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := bytes.Repeat([]byte("b"), 8000)
+	if _, err := wfs.WriteFile(mfs, "testdata/test.txt", want, fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fsys.ReadFile("test.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error ReadFile after overwritten returns %d bytes, %v; want %v`, len(got), err, fs.ErrNotExist)
+	}
+	got, err := fsys.ReadFile("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf(`Error ReadFile after the stale attrs returns %d bytes; want %d`, len(got), len(want))
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	c := &countClient{gcsClient: &fsClient{fsys: osfs.New(".")}}
+	fsys := &GCSFS{
+		VerifyChecksum: true,
+		bucket:         "testdata",
+		c:              c,
+	}
+
+	tests := []struct {
+		readFirst int
+		want      string
+	}{
+		{
+			readFirst: 0,
+			want:      "content01\n",
+		}, {
+			readFirst: 3,
+			want:      "tent01\n",
+		},
+	}
+	for _, test := range tests {
+		c.readersCount = 0
+		f, err := fsys.Open("dir0/file01.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.readFirst > 0 {
+			if _, err := io.ReadFull(f, make([]byte, test.readFirst)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var dst bytes.Buffer
+		n, err := io.Copy(&dst, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dst.String(); got != test.want || n != int64(len(test.want)) {
+			t.Errorf(`Error io.Copy copies %d bytes %q; want %q`, n, got, test.want)
+		}
+		if c.readersCount != 1 {
+			t.Errorf(`Error io.Copy opens readers %d times; want 1`, c.readersCount)
+		}
+		if n, err := io.Copy(&dst, f); n != 0 || err != nil {
+			t.Errorf(`Error io.Copy at EOF returns (%d, %v); want (0, nil)`, n, err)
+		}
+		f.Close()
+	}
+
+	f, err := fsys.openFile(fsys.Context(), "dir0/file01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.attrs.CRC32C++
+	if _, err := io.Copy(io.Discard, f); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf(`Error io.Copy returns %v; want %v`, err, ErrChecksumMismatch)
+	}
+}
+
+func TestGlobDoubleStar(t *testing.T) {
+	c := &countClient{gcsClient: &fsClient{fsys: osfs.New(t.TempDir())}}
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      c,
+	}
+	for _, name := range []string{
+		"logs/a.json",
+		"logs/2024/b.json",
+		"logs/2024/01/c.json",
+		"logs/2024/01/d.txt",
+		"other/e.json",
+	} {
+		if _, err := fsys.WriteFile(name, []byte(name), fs.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{
+			pattern: "**/*.json",
+			want:    []string{"logs/2024/01/c.json", "logs/2024/b.json", "logs/a.json", "other/e.json"},
+		}, {
+			pattern: "logs/**/*.json",
+			want:    []string{"logs/2024/01/c.json", "logs/2024/b.json", "logs/a.json"},
+		}, {
+			pattern: "logs/2024/**",
+			want:    []string{"logs/2024", "logs/2024/01", "logs/2024/01/c.json", "logs/2024/01/d.txt", "logs/2024/b.json"},
+		},
+	}
+	for _, test := range tests {
+		c.objectsCount = 0
+		got, err := fsys.Glob(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf(`Error Glob(%s) returns %v; want %v`, test.pattern, got, test.want)
+		}
+		if c.objectsCount != 1 {
+			t.Errorf(`Error Glob(%s) lists objects %d times; want 1`, test.pattern, c.objectsCount)
+		}
 	}
 }
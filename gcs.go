@@ -15,13 +15,18 @@ type gcsClient interface {
 type gcsBucket interface {
 	object(name string) gcsObject
 	objects(ctx context.Context, q *storage.Query) gcsObjectItetator
+	copy(ctx context.Context, dst, src string, srcGen int64) error
+	userProject(projectID string) gcsBucket
+	encryptionKey(key []byte) gcsBucket
+	retryer(opts ...storage.RetryOption) gcsBucket
 }
 
 type gcsObject interface {
 	attrs(ctx context.Context) (*storage.ObjectAttrs, error)
-	newReader(ctx context.Context) (io.ReadCloser, error)
-	newWriter(ctx context.Context) io.WriteCloser
-	delete(ctx context.Context) error
+	newReader(ctx context.Context, gen int64) (io.ReadCloser, error)
+	newRangeReader(ctx context.Context, gen, offset, length int64) (io.ReadCloser, error)
+	newWriter(ctx context.Context, attrs *storage.ObjectAttrs, conds *storage.Conditions) io.WriteCloser
+	delete(ctx context.Context, conds *storage.Conditions) error
 }
 
 type gcsObjectItetator interface {
@@ -43,35 +48,93 @@ func (c *storageClient) close() error {
 }
 
 type storageBucket struct {
-	b *storage.BucketHandle
+	b   *storage.BucketHandle
+	key []byte
+}
+
+func (b *storageBucket) handle(name string) *storage.ObjectHandle {
+	obj := b.b.Object(name)
+	if b.key != nil {
+		obj = obj.Key(b.key)
+	}
+	return obj
 }
 
 func (b *storageBucket) object(name string) gcsObject {
-	return &storageObject{obj: b.b.Object(name)}
+	return &storageObject{obj: b.handle(name)}
 }
 
 func (b *storageBucket) objects(ctx context.Context, q *storage.Query) gcsObjectItetator {
 	return &storageObjectIterator{itr: b.b.Objects(ctx, q)}
 }
 
+func (b *storageBucket) copy(ctx context.Context, dst, src string, srcGen int64) error {
+	srcObj := b.handle(src)
+	if srcGen > 0 {
+		srcObj = srcObj.Generation(srcGen)
+	}
+	_, err := b.handle(dst).CopierFrom(srcObj).Run(ctx)
+	return toObjectNotExistIfNotFound(err)
+}
+
+func (b *storageBucket) userProject(projectID string) gcsBucket {
+	return &storageBucket{b: b.b.UserProject(projectID), key: b.key}
+}
+
+func (b *storageBucket) encryptionKey(key []byte) gcsBucket {
+	return &storageBucket{b: b.b, key: key}
+}
+
+func (b *storageBucket) retryer(opts ...storage.RetryOption) gcsBucket {
+	return &storageBucket{b: b.b.Retryer(opts...), key: b.key}
+}
+
 type storageObject struct {
 	obj *storage.ObjectHandle
 }
 
-func (o *storageObject) newReader(ctx context.Context) (io.ReadCloser, error) {
-	return o.obj.NewReader(ctx)
+// generation returns the handle of the specified generation. If gen is 0 then
+// the handle refers to the latest generation.
+func (o *storageObject) generation(gen int64) *storage.ObjectHandle {
+	if gen > 0 {
+		return o.obj.Generation(gen)
+	}
+	return o.obj
+}
+
+func (o *storageObject) newReader(ctx context.Context, gen int64) (io.ReadCloser, error) {
+	return o.generation(gen).NewReader(ctx)
+}
+
+func (o *storageObject) newRangeReader(ctx context.Context, gen, offset, length int64) (io.ReadCloser, error) {
+	return o.generation(gen).NewRangeReader(ctx, offset, length)
 }
 
-func (o *storageObject) newWriter(ctx context.Context) io.WriteCloser {
-	return o.obj.NewWriter(ctx)
+func (o *storageObject) newWriter(ctx context.Context, attrs *storage.ObjectAttrs, conds *storage.Conditions) io.WriteCloser {
+	obj := o.obj
+	if conds != nil {
+		obj = obj.If(*conds)
+	}
+	w := obj.NewWriter(ctx)
+	if attrs != nil {
+		w.ContentType = attrs.ContentType
+		w.CacheControl = attrs.CacheControl
+		w.ContentEncoding = attrs.ContentEncoding
+		w.Metadata = attrs.Metadata
+	}
+	return w
 }
 
 func (o *storageObject) attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
 	return o.obj.Attrs(ctx)
 }
 
-func (o *storageObject) delete(ctx context.Context) error {
-	return o.obj.Delete(ctx)
+func (o *storageObject) delete(ctx context.Context, conds *storage.Conditions) error {
+	obj := o.obj
+	if conds != nil {
+		obj = obj.If(*conds)
+	}
+	return obj.Delete(ctx)
 }
 
 type storageObjectIterator struct {
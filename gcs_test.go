@@ -3,20 +3,29 @@ package gcsfs
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/jarxorg/wfs/osfs"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 type mockTransport struct {
 	gotReq  *http.Request
+	gotReqs []*http.Request
 	gotBody []byte
 	results []transportResult
 }
@@ -28,6 +37,7 @@ type transportResult struct {
 
 func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	t.gotReq = req
+	t.gotReqs = append(t.gotReqs, req)
 	t.gotBody = nil
 	if req.Body != nil {
 		bytes, err := io.ReadAll(req.Body)
@@ -56,6 +66,161 @@ func bodyReader(s string) io.ReadCloser {
 	return io.NopCloser(strings.NewReader(s))
 }
 
+func TestGCSNewWithOptions(t *testing.T) {
+	fsys := NewWithOptions("bucket", option.WithHTTPClient(&http.Client{Transport: &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4"}`),
+			}},
+		},
+	}}))
+	defer fsys.Close()
+
+	info, err := fsys.Stat("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 4 {
+		t.Errorf(`Error Size() returns %d; want 4`, info.Size())
+	}
+}
+
+func TestGCSEmulatorHost(t *testing.T) {
+	var gotPath string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"name":"test.txt","size":"4"}`)
+	}))
+	defer s.Close()
+
+	if err := os.Setenv("STORAGE_EMULATOR_HOST", s.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("STORAGE_EMULATOR_HOST")
+
+	fsys := NewWithOptions("bucket")
+	defer fsys.Close()
+
+	if _, err := fsys.Stat("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/storage/v1/b/bucket/o/test.txt"; gotPath != want {
+		t.Errorf(`Error request path %q; want %q`, gotPath, want)
+	}
+}
+
+func TestGCSUserProject(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4"}`),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m)).WithUserProject("project")
+	defer fsys.Close()
+
+	if _, err := fsys.Stat("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.gotReq.URL.Query().Get("userProject"); got != "project" {
+		t.Errorf(`Error Stat userProject %q; want "project"`, got)
+	}
+	if _, err := fsys.ReadDir("."); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.gotReq.URL.Query().Get("userProject"); got != "project" {
+		t.Errorf(`Error ReadDir userProject %q; want "project"`, got)
+	}
+}
+
+func TestGCSEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4"}`),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader("test"),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m)).WithEncryptionKey(key)
+	defer fsys.Close()
+
+	if _, err := fsys.ReadFile("test.txt"); err != nil {
+		t.Fatal(err)
+	}
+	want := base64.StdEncoding.EncodeToString(key)
+	for _, req := range m.gotReqs {
+		if got := req.Header.Get("X-Goog-Encryption-Key"); got != want {
+			t.Errorf(`Error %s %s encryption key %q; want %q`, req.Method, req.URL.Path, got, want)
+		}
+	}
+}
+
+func TestGCSEncryptionKeyWrongKey(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       bodyReader(`{"error":{"code":400,"message":"The provided encryption key is incorrect."}}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m)).WithEncryptionKey(bytes.Repeat([]byte{2}, 32))
+	defer fsys.Close()
+
+	_, err := fsys.Open("test.txt")
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Errorf(`Error Open returns %v; want the error of GCS`, err)
+	}
+}
+
+func TestGCSEncryptionKeyInvalidLength(t *testing.T) {
+	fsys := NewWithClient("bucket", mockClient(t, &mockTransport{})).WithEncryptionKey([]byte("short"))
+	defer fsys.Close()
+
+	if _, err := fsys.Stat("test.txt"); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf(`Error Stat returns %v; want %v`, err, ErrInvalidEncryptionKey)
+	}
+}
+
+func TestGCSRetry(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       bodyReader("{}"),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4"}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m)).WithRetry(storage.WithPolicy(storage.RetryNever))
+	defer fsys.Close()
+
+	if _, err := fsys.Stat("test.txt"); err == nil {
+		t.Errorf(`Error Stat returns no error`)
+	}
+	if got := len(m.gotReqs); got != 1 {
+		t.Errorf(`Error Stat sends %d requests; want 1`, got)
+	}
+}
+
 func TestGCSRead(t *testing.T) {
 	want := []byte(`test`)
 
@@ -70,7 +235,7 @@ func TestGCSRead(t *testing.T) {
 	defer c.close()
 
 	ctx := context.Background()
-	in, err := c.bucket("bucket").object("test.txt").newReader(ctx)
+	in, err := c.bucket("bucket").object("test.txt").newReader(ctx, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,6 +251,135 @@ func TestGCSRead(t *testing.T) {
 	}
 }
 
+func TestGCSRangeRead(t *testing.T) {
+	want := []byte(`st`)
+
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusPartialContent,
+				Header:     http.Header{"Content-Range": {"bytes 2-3/4"}},
+				Body:       io.NopCloser(bytes.NewBuffer(want)),
+			}},
+		},
+	}
+	c := storageClient{c: mockClient(t, m)}
+	defer c.close()
+
+	ctx := context.Background()
+	in, err := c.bucket("bucket").object("test.txt").newRangeReader(ctx, 0, 2, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	got, err := io.ReadAll(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Error got %v; want %v", want, got)
+	}
+	if gotRange := m.gotReq.Header.Get("Range"); gotRange != "bytes=2-" {
+		t.Errorf(`Error Range header %q; want "bytes=2-"`, gotRange)
+	}
+}
+
+func TestGCSFileReadGeneration(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4","generation":"1234"}`),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader("test"),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	got, err := fsys.ReadFile("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "test"; string(got) != want {
+		t.Errorf(`Error ReadFile returns %q; want %q`, got, want)
+	}
+	if gen := m.gotReq.URL.Query().Get("generation"); gen != "1234" {
+		t.Errorf(`Error generation of the read request %q; want "1234"`, gen)
+	}
+}
+
+func TestGCSFileReadAt(t *testing.T) {
+	fsys := NewWithClient("bucket", mockClient(t, &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusPartialContent,
+				Header:     http.Header{"Content-Range": {"bytes 2-3/4"}},
+				Body:       bodyReader("st"),
+			}},
+		},
+	}))
+	defer fsys.Close()
+
+	c, err := fsys.client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := &storage.ObjectAttrs{Name: "test.txt", Size: 4}
+	f := newGcsFile(fsys.Context(), fsys, c.bucket("bucket").object("test.txt"), attrs)
+
+	p := make([]byte, 4)
+	n, err := f.ReadAt(p, 2)
+	if err != io.EOF {
+		t.Errorf(`Error ReadAt returns error %v; want %v`, err, io.EOF)
+	}
+	if got := string(p[:n]); got != "st" {
+		t.Errorf(`Error ReadAt read %q; want "st"`, got)
+	}
+
+	n, err = f.ReadAt(p, 4)
+	if n != 0 || err != io.EOF {
+		t.Errorf(`Error ReadAt at end returns (%d, %v); want (0, %v)`, n, err, io.EOF)
+	}
+}
+
+func TestGCSSignedURL(t *testing.T) {
+	fsys := NewWithClient("bucket", mockClient(t, &mockTransport{}))
+	defer fsys.Close()
+
+	url, err := fsys.SignedURL("dir/test.txt", &storage.SignedURLOptions{
+		GoogleAccessID: "test@example.com",
+		SignBytes: func(b []byte) ([]byte, error) {
+			return []byte("signature"), nil
+		},
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(url, "/bucket/dir/test.txt?") {
+		t.Errorf(`Error SignedURL returns %q; want the url of bucket/dir/test.txt`, url)
+	}
+}
+
+func TestGCSSignedURLNotStorageClient(t *testing.T) {
+	fsys := &GCSFS{
+		bucket: "testdata",
+		c:      &fsClient{fsys: osfs.New(".")},
+	}
+
+	_, err := fsys.SignedURL("file0.txt", &storage.SignedURLOptions{})
+	if !errors.Is(err, ErrNotStorageClient) {
+		t.Errorf(`Error SignedURL returns %v; want %v`, err, ErrNotStorageClient)
+	}
+}
+
 func TestGCSAttrs(t *testing.T) {
 	c := storageClient{c: mockClient(t, &mockTransport{
 		results: []transportResult{
@@ -104,6 +398,100 @@ func TestGCSAttrs(t *testing.T) {
 	}
 }
 
+func TestGCSStatSys(t *testing.T) {
+	want := "text/plain"
+
+	fsys := NewWithClient("bucket", mockClient(t, &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4","contentType":"` + want + `"}`),
+			}},
+		},
+	}))
+	defer fsys.Close()
+
+	info, err := fsys.Stat("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, ok := info.Sys().(*storage.ObjectAttrs)
+	if !ok {
+		t.Fatalf(`Error Sys() returns %T; want *storage.ObjectAttrs`, info.Sys())
+	}
+	if attrs.ContentType != want {
+		t.Errorf(`Error ContentType %q; want %q`, attrs.ContentType, want)
+	}
+	if info.Size() != 4 {
+		t.Errorf(`Error Size() returns %d; want 4`, info.Size())
+	}
+}
+
+func TestGCSStatGeneration(t *testing.T) {
+	fsys := NewWithClient("bucket", mockClient(t, &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt","size":"4","generation":"1234","metageneration":"2"}`),
+			}},
+		},
+	}))
+	defer fsys.Close()
+
+	info, err := fsys.Stat("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi, ok := info.(GCSFileInfo)
+	if !ok {
+		t.Fatalf(`Error Stat returns %T; want GCSFileInfo`, info)
+	}
+	if got := gi.Generation(); got != 1234 {
+		t.Errorf(`Error Generation() returns %d; want 1234`, got)
+	}
+	if got := gi.Metageneration(); got != 2 {
+		t.Errorf(`Error Metageneration() returns %d; want 2`, got)
+	}
+}
+
+func TestGCSReadDirGeneration(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"prefixes":["dir/sub/"],"items":[{"name":"dir/test.txt","size":"4","generation":"1234","metageneration":"2"}]}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := m.gotReq.URL.Query().Get("fields"); !strings.Contains(fields, "generation") || !strings.Contains(fields, "metageneration") {
+		t.Errorf(`Error fields of the list request %q; want generation and metageneration`, fields)
+	}
+	want := map[string][2]int64{
+		"sub":      {0, 0},
+		"test.txt": {1234, 2},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf(`Error ReadDir returns %d entries; want %d`, len(entries), len(want))
+	}
+	for _, entry := range entries {
+		gi, ok := entry.(GCSFileInfo)
+		if !ok {
+			t.Fatalf(`Error ReadDir returns %T; want GCSFileInfo`, entry)
+		}
+		got := [2]int64{gi.Generation(), gi.Metageneration()}
+		if got != want[entry.Name()] {
+			t.Errorf(`Error generations of %s %v; want %v`, entry.Name(), got, want[entry.Name()])
+		}
+	}
+}
+
 func TestGCSWrite(t *testing.T) {
 	c := storageClient{c: mockClient(t, &mockTransport{
 		results: []transportResult{
@@ -116,7 +504,7 @@ func TestGCSWrite(t *testing.T) {
 	defer c.close()
 
 	ctx := context.Background()
-	out := c.bucket("bucket").object("test.txt").newWriter(ctx)
+	out := c.bucket("bucket").object("test.txt").newWriter(ctx, nil, nil)
 	defer out.Close()
 
 	_, err := out.Write([]byte("test"))
@@ -125,6 +513,114 @@ func TestGCSWrite(t *testing.T) {
 	}
 }
 
+func TestGCSWriteWithAttrs(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("{}")),
+			}},
+		},
+	}
+	c := storageClient{c: mockClient(t, m)}
+	defer c.close()
+
+	ctx := context.Background()
+	out := c.bucket("bucket").object("test.html").newWriter(ctx, &storage.ObjectAttrs{
+		ContentType:  "text/html",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"key": "value"},
+	}, nil)
+	if _, err := out.Write([]byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`"contentType":"text/html"`,
+		`"cacheControl":"no-cache"`,
+		`"metadata":{"key":"value"}`,
+	} {
+		if !bytes.Contains(m.gotBody, []byte(want)) {
+			t.Errorf(`Error request body %q does not contain %q`, m.gotBody, want)
+		}
+	}
+}
+
+func TestGCSWriteWithConditions(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusPreconditionFailed,
+				Body:       bodyReader("{}"),
+			}},
+		},
+	}
+	c := storageClient{c: mockClient(t, m)}
+	defer c.close()
+
+	fsys := New("bucket")
+	f := newGcsWriterFile(fsys.Context(), fsys, c.bucket("bucket").object("test.txt"), "test.txt", nil, &storage.Conditions{GenerationMatch: 5})
+	if _, err := f.Write([]byte("test")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf(`Error Close returns %v; want %v`, err, ErrPreconditionFailed)
+	}
+	if got := m.gotReq.URL.Query().Get("ifGenerationMatch"); got != "5" {
+		t.Errorf(`Error ifGenerationMatch %q; want "5"`, got)
+	}
+}
+
+func TestGCSWriteFileCloseError(t *testing.T) {
+	m := &mockTransport{
+		results: append(writableResults(),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusForbidden,
+				Body:       bodyReader("{}"),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	fsys.Timeout = time.Minute
+	defer fsys.Close()
+
+	n, err := fsys.WriteFile("test.txt", []byte("test"), fs.ModePerm)
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "WriteFile" {
+		t.Errorf(`Error WriteFile returns %d, %v; want *fs.PathError of WriteFile`, n, err)
+	}
+}
+
+func TestGCSWriteWithZeroConditions(t *testing.T) {
+	m := &mockTransport{
+		results: append(writableResults(),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"test.txt"}`),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	f, err := fsys.CreateFileWithConditions("test.txt", nil, storage.Conditions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("test")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.gotReq.URL.Query().Get("ifGenerationMatch"); got != "" {
+		t.Errorf(`Error ifGenerationMatch %q; want ""`, got)
+	}
+}
+
 func TestGCSDelete(t *testing.T) {
 	c := storageClient{c: mockClient(t, &mockTransport{
 		results: []transportResult{
@@ -137,12 +633,243 @@ func TestGCSDelete(t *testing.T) {
 	defer c.close()
 
 	ctx := context.Background()
-	err := c.bucket("bucket").object("test.txt").delete(ctx)
+	err := c.bucket("bucket").object("test.txt").delete(ctx, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
+// writableResults returns the results for checking that the destination is
+// neither a file nor a directory and its parent is not a file.
+func writableResults() []transportResult {
+	return []transportResult{
+		{res: &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       bodyReader("{}"),
+		}},
+		{res: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       bodyReader("{}"),
+		}},
+		{res: &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       bodyReader("{}"),
+		}},
+	}
+}
+
+func TestGCSCopy(t *testing.T) {
+	m := &mockTransport{
+		results: append(writableResults(),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"done":true,"resource":{"name":"dir/dst.txt"}}`),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	if err := fsys.Copy("dir/dst.txt", "src.txt"); err != nil {
+		t.Fatal(err)
+	}
+	want := "/b/bucket/o/src.txt/rewriteTo/b/bucket/o/dir/dst.txt"
+	if got := m.gotReq.URL.Path; !strings.HasSuffix(got, want) {
+		t.Errorf(`Error request path %q; want suffix %q`, got, want)
+	}
+}
+
+// renameSrcResults returns the results for getting the attributes of src.txt.
+func renameSrcResults() []transportResult {
+	return []transportResult{
+		{res: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       bodyReader(`{"name":"src.txt","size":"4","generation":"7"}`),
+		}},
+	}
+}
+
+func TestGCSRename(t *testing.T) {
+	m := &mockTransport{
+		results: append(append(renameSrcResults(), writableResults()...),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"done":true,"resource":{"name":"dst.txt"}}`),
+			}},
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       bodyReader(""),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	if err := fsys.Rename("dst.txt", "src.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if m.gotReq.Method != http.MethodDelete || !strings.HasSuffix(m.gotReq.URL.Path, "/b/bucket/o/src.txt") {
+		t.Errorf(`Error last request %s %s; want DELETE of src.txt`, m.gotReq.Method, m.gotReq.URL.Path)
+	}
+	if got := m.gotReq.URL.Query().Get("ifGenerationMatch"); got != "7" {
+		t.Errorf(`Error ifGenerationMatch of DELETE %q; want "7"`, got)
+	}
+	for _, req := range m.gotReqs {
+		if strings.Contains(req.URL.Path, "/rewriteTo/") {
+			if got := req.URL.Query().Get("sourceGeneration"); got != "7" {
+				t.Errorf(`Error sourceGeneration of the copy %q; want "7"`, got)
+			}
+		}
+	}
+}
+
+func TestGCSRenameReplaced(t *testing.T) {
+	m := &mockTransport{
+		results: append(append(renameSrcResults(), writableResults()...),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"done":true,"resource":{"name":"dst.txt"}}`),
+			}},
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusPreconditionFailed,
+				Body:       bodyReader("{}"),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	if err := fsys.Rename("dst.txt", "src.txt"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf(`Error Rename returns %v; want %v`, err, ErrPreconditionFailed)
+	}
+}
+
+func TestGCSRenameCopyError(t *testing.T) {
+	m := &mockTransport{
+		results: append(append(renameSrcResults(), writableResults()...),
+			transportResult{res: &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       bodyReader("{}"),
+			}},
+		),
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	if err := fsys.Rename("dst.txt", "src.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(`Error Rename returns %v; want %v`, err, fs.ErrNotExist)
+	}
+	if m.gotReq.Method == http.MethodDelete {
+		t.Errorf(`Error Rename deleted the source after the copy failed`)
+	}
+}
+
+func TestGCSMkdirAllDirMarker(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       bodyReader("{}"),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"name":"dir/"}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	fsys.CreateDirMarkers = true
+	defer fsys.Close()
+
+	if err := fsys.MkdirAll("dir", fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"name":"dir/"`; !bytes.Contains(m.gotBody, []byte(want)) {
+		t.Errorf(`Error request body %q does not contain %q`, m.gotBody, want)
+	}
+}
+
+func TestGCSReadDirDirMarker(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"items":[{"name":"dir/"},{"name":"dir/a.txt","size":"1"}],"prefixes":["dir/sub/"]}`),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       bodyReader("{}"),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"items":[{"name":"empty/"}]}`),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	defer fsys.Close()
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if want := []string{"a.txt", "sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`Error ReadDir returns %v; want %v`, got, want)
+	}
+
+	info, err := fsys.Stat("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() {
+		t.Errorf(`Error Stat of the empty directory returns a file`)
+	}
+}
+
+func TestGCSRemoveAllDirMarker(t *testing.T) {
+	m := &mockTransport{
+		results: []transportResult{
+			{res: &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       bodyReader("{}"),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       bodyReader(`{"items":[{"name":"dir/"},{"name":"dir/a.txt","size":"1"}]}`),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       bodyReader(""),
+			}},
+			{res: &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       bodyReader(""),
+			}},
+		},
+	}
+	fsys := NewWithClient("bucket", mockClient(t, m))
+	fsys.RemoveAllConcurrency = 1
+	defer fsys.Close()
+
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, req := range m.gotReqs {
+		if req.Method == http.MethodDelete {
+			got = append(got, req.URL.Path)
+		}
+	}
+	want := []string{"/storage/v1/b/bucket/o/dir/", "/storage/v1/b/bucket/o/dir/a.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(`Error RemoveAll deletes %v; want %v`, got, want)
+	}
+}
+
 func TestGCSObjects(t *testing.T) {
 	c := storageClient{c: mockClient(t, &mockTransport{
 		results: []transportResult{
@@ -1,12 +1,17 @@
 package gcsfs
 
 import (
+	"context"
 	"errors"
 	"io/fs"
+	"net/http"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 )
 
 func isNotExist(err error) bool {
@@ -41,6 +46,27 @@ func toObjectNotExistIfNoExist(err error) error {
 	return err
 }
 
+func toObjectNotExistIfNotFound(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return storage.ErrObjectNotExist
+	}
+	return err
+}
+
+func toPreconditionFailedIfFailed(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return ErrPreconditionFailed
+	}
+	return err
+}
+
+// isDirMarker reports whether attrs is a zero-byte directory marker object named "<dir>/".
+func isDirMarker(attrs *storage.ObjectAttrs) bool {
+	return attrs.Name != "" && strings.HasSuffix(attrs.Name, "/")
+}
+
 func normalizePrefix(prefix string) string {
 	prefix = path.Clean(prefix)
 	if prefix == "." || prefix == "/" {
@@ -69,6 +95,10 @@ LOOP:
 	return joined
 }
 
+// queryAttrSelection is the attributes of the listed objects. The prefixes are
+// always listed.
+var queryAttrSelection = []string{"Name", "Size", "Updated", "Generation", "Metageneration"}
+
 func newQuery(delim, prefix, offset string) *storage.Query {
 	query := &storage.Query{
 		Delimiter:                delim,
@@ -76,17 +106,45 @@ func newQuery(delim, prefix, offset string) *storage.Query {
 		StartOffset:              offset,
 		IncludeTrailingDelimiter: delim == "/",
 	}
-	query.SetAttrSelection([]string{"Prefix", "Name", "Size", "Updated"})
+	query.SetAttrSelection(queryAttrSelection)
 	return query
 }
 
-func contains(keys []string, key string) bool {
-	for _, k := range keys {
+func indexOf(keys []string, key string) int {
+	for i, k := range keys {
 		if k == key {
-			return true
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(keys []string, key string) bool {
+	return indexOf(keys, key) != -1
+}
+
+// matchSegments reports whether the path elements match the pattern elements.
+// The pattern element "**" matches zero or more path elements, and the other
+// pattern elements are matched by path.Match.
+func matchSegments(patterns, elems []string) bool {
+	if len(patterns) == 0 {
+		return len(elems) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchSegments(patterns[1:], elems[i:]) {
+				return true
+			}
 		}
+		return false
 	}
-	return false
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(patterns[0], elems[0]); !ok {
+		return false
+	}
+	return matchSegments(patterns[1:], elems[1:])
 }
 
 func appendIfMatch(keys []string, key, pattern string) []string {
@@ -95,3 +153,129 @@ func appendIfMatch(keys []string, key, pattern string) []string {
 	}
 	return keys
 }
+
+// workerPool calls fn for each name sent to the pool by the specified number of
+// workers. The first error returned by fn cancels the context of the pool. If the
+// context is done before fn is called then the error is a PathError of op and
+// the name.
+type workerPool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	names  chan string
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+func newWorkerPool(ctx context.Context, op string, concurrency int, fn func(ctx context.Context, name string) error) *workerPool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &workerPool{
+		ctx:    ctx,
+		cancel: cancel,
+		names:  make(chan string),
+	}
+	for i := 0; i < concurrency; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for name := range p.names {
+				if err := ctx.Err(); err != nil {
+					p.fail(toPathError(err, op, name))
+					continue
+				}
+				if err := fn(ctx, name); err != nil {
+					p.fail(err)
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// fail records the first error and cancels the context of the pool.
+func (p *workerPool) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// send sends the name to the workers. send returns false if the context of the
+// pool is done.
+func (p *workerPool) send(name string) bool {
+	select {
+	case p.names <- name:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// wait waits for all workers to finish and returns the first error.
+func (p *workerPool) wait() error {
+	close(p.names)
+	p.wg.Wait()
+	p.cancel()
+	return p.err
+}
+
+// attrsCache is a TTL-bounded cache of object attributes keyed by object key.
+// attrsCache is safe for concurrent use.
+type attrsCache struct {
+	mutex   sync.Mutex
+	entries map[string]attrsCacheEntry
+	// swept is the time when the expired entries were removed last.
+	swept time.Time
+}
+
+type attrsCacheEntry struct {
+	attrs   *storage.ObjectAttrs
+	expires time.Time
+}
+
+func newAttrsCache() *attrsCache {
+	return &attrsCache{entries: map[string]attrsCacheEntry{}}
+}
+
+// get returns the cached attributes of the key if they have not expired.
+func (c *attrsCache) get(key string) (*storage.ObjectAttrs, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.attrs, true
+}
+
+// set caches the attributes of the key for the specified ttl. set also removes
+// the expired entries at most once per ttl so that the cache does not grow with
+// keys that are never read again.
+func (c *attrsCache) set(key string, attrs *storage.ObjectAttrs, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.swept) >= ttl {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[key] = attrsCacheEntry{attrs: attrs, expires: now.Add(ttl)}
+}
+
+// delete removes the cached attributes of the key.
+func (c *attrsCache) delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
@@ -1,11 +1,18 @@
 package gcsfs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io/fs"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 )
 
 func TestIsNotExist(t *testing.T) {
@@ -110,6 +117,78 @@ func TestToObjectNotExistIfNoExist(t *testing.T) {
 	}
 }
 
+func TestToObjectNotExistIfNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{
+			err:  &googleapi.Error{Code: http.StatusNotFound},
+			want: storage.ErrObjectNotExist,
+		}, {
+			err:  &googleapi.Error{Code: http.StatusForbidden},
+			want: &googleapi.Error{Code: http.StatusForbidden},
+		}, {
+			err:  nil,
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		got := toObjectNotExistIfNotFound(test.err)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf(`Error toObjectNotExistIfNotFound(%v) returns %v; want %v`, test.err, got, test.want)
+		}
+	}
+}
+
+func TestToPreconditionFailedIfFailed(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{
+			err:  &googleapi.Error{Code: http.StatusPreconditionFailed},
+			want: ErrPreconditionFailed,
+		}, {
+			err:  &googleapi.Error{Code: http.StatusNotFound},
+			want: &googleapi.Error{Code: http.StatusNotFound},
+		}, {
+			err:  nil,
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		got := toPreconditionFailedIfFailed(test.err)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf(`Error toPreconditionFailedIfFailed(%v) returns %v; want %v`, test.err, got, test.want)
+		}
+	}
+}
+
+func TestIsDirMarker(t *testing.T) {
+	tests := []struct {
+		attrs *storage.ObjectAttrs
+		want  bool
+	}{
+		{
+			attrs: &storage.ObjectAttrs{Name: "dir/"},
+			want:  true,
+		}, {
+			attrs: &storage.ObjectAttrs{Name: "dir/file.txt"},
+			want:  false,
+		}, {
+			attrs: &storage.ObjectAttrs{Prefix: "dir/"},
+			want:  false,
+		},
+	}
+	for _, test := range tests {
+		got := isDirMarker(test.attrs)
+		if got != test.want {
+			t.Errorf(`Error isDirMarker(%v) returns %v; want %v`, test.attrs, got, test.want)
+		}
+	}
+}
+
 func TestNormalizePrefix(t *testing.T) {
 	tests := []struct {
 		prefix string
@@ -201,7 +280,9 @@ func TestNewQuery(t *testing.T) {
 		StartOffset:              "offset",
 		IncludeTrailingDelimiter: true,
 	}
-	want.SetAttrSelection([]string{"Prefix", "Name", "Size", "Updated"})
+	if err := want.SetAttrSelection(queryAttrSelection); err != nil {
+		t.Fatal(err)
+	}
 
 	got := newQuery(want.Delimiter, want.Prefix, want.StartOffset)
 	if !reflect.DeepEqual(got, want) {
@@ -232,3 +313,108 @@ func TestContains(t *testing.T) {
 		}
 	}
 }
+
+func TestAttrsCache(t *testing.T) {
+	c := newAttrsCache()
+	want := &storage.ObjectAttrs{Name: "test.txt"}
+
+	if _, ok := c.get("test.txt"); ok {
+		t.Errorf(`Error get returns the attrs before set`)
+	}
+	c.set("test.txt", want, time.Hour)
+	if got, ok := c.get("test.txt"); !ok || got != want {
+		t.Errorf(`Error get returns %v, %v; want %v, true`, got, ok, want)
+	}
+	c.delete("test.txt")
+	if _, ok := c.get("test.txt"); ok {
+		t.Errorf(`Error get returns the attrs after delete`)
+	}
+	c.set("test.txt", want, -time.Second)
+	if _, ok := c.get("test.txt"); ok {
+		t.Errorf(`Error get returns the expired attrs`)
+	}
+}
+
+func TestAttrsCacheSweep(t *testing.T) {
+	c := newAttrsCache()
+	for i := 0; i < 10; i++ {
+		c.set(fmt.Sprintf("file%d.txt", i), &storage.ObjectAttrs{}, time.Millisecond)
+	}
+	time.Sleep(2 * time.Millisecond)
+	c.set("test.txt", &storage.ObjectAttrs{}, time.Millisecond)
+	if got := len(c.entries); got != 1 {
+		t.Errorf(`Error entries after set %d; want 1`, got)
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{
+			pattern: "**/*.json",
+			name:    "a.json",
+			want:    true,
+		}, {
+			pattern: "**/*.json",
+			name:    "a/b/c.json",
+			want:    true,
+		}, {
+			pattern: "logs/**/*.json",
+			name:    "logs/a.json",
+			want:    true,
+		}, {
+			pattern: "logs/**/*.json",
+			name:    "logs/2024/01/a.json",
+			want:    true,
+		}, {
+			pattern: "logs/**/*.json",
+			name:    "other/a.json",
+			want:    false,
+		}, {
+			pattern: "logs/**",
+			name:    "logs/2024/01",
+			want:    true,
+		}, {
+			pattern: "logs/*",
+			name:    "logs/2024/01",
+			want:    false,
+		}, {
+			pattern: "logs/**/01/*.txt",
+			name:    "logs/2024/01/a.json",
+			want:    false,
+		},
+	}
+	for _, test := range tests {
+		got := matchSegments(strings.Split(test.pattern, "/"), strings.Split(test.name, "/"))
+		if got != test.want {
+			t.Errorf(`Error matchSegments(%s, %s) returns %v; want %v`, test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestWorkerPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	pool := newWorkerPool(ctx, "test", 1, func(ctx context.Context, name string) error {
+		called = true
+		return nil
+	})
+	// NOTE: Send to the worker directly because send returns false when the
+	// context is done.
+	pool.names <- "test.txt"
+	err := pool.wait()
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf(`Error wait returns %v; want *fs.PathError of %v`, err, context.Canceled)
+	}
+	if pathErr.Op != "test" || pathErr.Path != "test.txt" {
+		t.Errorf(`Error wait returns %v; want op test path test.txt`, err)
+	}
+	if called {
+		t.Errorf(`Error fn is called after the context is done`)
+	}
+}